@@ -9,11 +9,13 @@ import (
 
 var (
 	// validMimeTypesList is a list of all acceptable MIME types that
-	// can be communicated between client and server.
+	// can be communicated between client and server.  PDF files are
+	// only accepted by politeiawww for invoices.
 	validMimeTypesList = []string{
 		"image/png",
 		"text/plain",
 		"text/plain; charset=utf-8",
+		"application/pdf",
 	}
 
 	// validMimeTypesMap is the same as ValidMimeTypesList, but structured
@@ -20,20 +20,22 @@ const (
 	RouteAdminInvoices    = "/admin/invoices"
 
 	// Invoice status codes
-	InvoiceStatusInvalid  InvoiceStatusT = 0 // Invalid status
-	InvoiceStatusNotFound InvoiceStatusT = 1 // Invoice not found
-	InvoiceStatusNew      InvoiceStatusT = 2 // Invoice has not been reviewed
-	InvoiceStatusUpdated  InvoiceStatusT = 3 // Invoice has unreviewed changes
-	InvoiceStatusDisputed InvoiceStatusT = 4 // Invoice has been disputed for some reason
-	InvoiceStatusRejected InvoiceStatusT = 5 // Invoice fully rejected and closed
-	InvoiceStatusApproved InvoiceStatusT = 6 // Invoice has been approved
-	InvoiceStatusPaid     InvoiceStatusT = 7 // Invoice has been paid
+	InvoiceStatusInvalid   InvoiceStatusT = 0 // Invalid status
+	InvoiceStatusNotFound  InvoiceStatusT = 1 // Invoice not found
+	InvoiceStatusNew       InvoiceStatusT = 2 // Invoice has not been reviewed
+	InvoiceStatusUpdated   InvoiceStatusT = 3 // Invoice has unreviewed changes
+	InvoiceStatusDisputed  InvoiceStatusT = 4 // Invoice has been disputed for some reason
+	InvoiceStatusRejected  InvoiceStatusT = 5 // Invoice fully rejected and closed
+	InvoiceStatusApproved  InvoiceStatusT = 6 // Invoice has been approved
+	InvoiceStatusPaid      InvoiceStatusT = 7 // Invoice has been paid
+	InvoiceStatusWithdrawn InvoiceStatusT = 8 // Invoice has been withdrawn by its author
 
 	// Line item types
 	LineItemTypeInvalid LineItemTypeT = 0 // Invalid type
 	LineItemTypeLabor   LineItemTypeT = 1 // Labor line items
 	LineItemTypeExpense LineItemTypeT = 2 // Expenses incurred line items
 	LineItemTypeMisc    LineItemTypeT = 3 // Catch all for anything else
+	LineItemTypeCredit  LineItemTypeT = 4 // Advances and other offsets
 )
 
 /// Contractor Management System Routes
@@ -73,6 +75,16 @@ type NewInvoice struct {
 	Files     []www.File `json:"files"`     // Invoice file and any attachments along with it
 	PublicKey string     `json:"publickey"` // Key used to verify signature
 	Signature string     `json:"signature"` // Signature of file hash
+
+	// Nonce identifies the request so that a retried request can be
+	// recognized.  It is the hex encoded SHA256 digest of the merkle
+	// root of the files followed by the decimal month and year.
+	Nonce string `json:"nonce,omitempty"`
+
+	// OnBehalfOf is the user ID of the contractor that the invoice is
+	// submitted for.  Only admins may set it.  The invoice is signed
+	// by the admin and attributed to the contractor.
+	OnBehalfOf string `json:"onbehalfof,omitempty"`
 }
 
 // NewInvoiceReply is used to reply to the NewInvoiceReply command.
@@ -107,9 +119,22 @@ type InvoiceRecord struct {
 	Files              []www.File     `json:"file"`                         // Actual invoice file
 	Version            string         `json:"version"`                      // Record version
 
+	// StatusChanges contains the status changes of the invoice in
+	// chronological order, starting with the submission of the invoice.
+	StatusChanges []InvoiceStatusChange `json:"statuschanges,omitempty"`
+
 	CensorshipRecord www.CensorshipRecord `json:"censorshiprecord"`
 }
 
+// InvoiceStatusChange is a change of the status of an invoice.
+type InvoiceStatusChange struct {
+	Status         InvoiceStatusT `json:"status"`                   // New status of the invoice
+	Reason         string         `json:"reason,omitempty"`         // Reason for the change
+	AdminPublicKey string         `json:"adminpublickey,omitempty"` // Public key of the admin that made the change
+	AdminUsername  string         `json:"adminusername,omitempty"`  // Username of the admin that made the change
+	Timestamp      int64          `json:"timestamp"`                // Timestamp of the change
+}
+
 // InvoiceDetails is used to retrieve a invoice by it's token.
 type InvoiceDetails struct {
 	Token string `json:"token"` // Censorship token
@@ -127,18 +152,38 @@ type InvoiceInput struct {
 	Month     uint16           `json:"month"` // Month of Invoice
 	Year      uint16           `json:"year"`  // Year of Invoice
 	LineItems []LineItemsInput `json:"lineitems"`
+
+	// ExchangeRate is the USD price of one DCR that the invoice is
+	// billed at, e.g. the average rate of the invoice month.  It is
+	// optional and is zero when it is not recorded.
+	ExchangeRate float64 `json:"exchangerate,omitempty"`
+
+	// CSVMetadata describes the csv that the line items were parsed
+	// from so that the line items can be rendered as csv again.
+	CSVMetadata *InvoiceCSVMetadata `json:"csvmetadata,omitempty"`
+}
+
+// InvoiceCSVMetadata contains the csv format that was used to parse the line
+// items of an invoice.
+type InvoiceCSVMetadata struct {
+	FieldDelimiterChar rune `json:"fielddelimiterchar"` // Line item field delimiter
+	CommentChar        rune `json:"commentchar"`        // Comment line prefix
 }
 
 // LineItemsInput is the expected struct of line items contained within an users'
 // invoice input.
 type LineItemsInput struct {
-	LineNumber    uint16        `json:"linenum"`       // Line number of the line item
-	Type          LineItemTypeT `json:"type"`          // Type of work performed
-	Subtype       string        `json:"subtype"`       // Subtype of work performed
-	Description   string        `json:"description"`   // Description of work performed
-	ProposalToken string        `json:"proposaltoken"` // Link to politeia proposal that work is associated with
-	Hours         float64       `json:"hours"`         // Number of Hours
-	TotalCost     float64       `json:"totalcost"`     // Total cost of line item
+	LineNumber    uint16        `json:"linenum"`             // Line number of the line item
+	Type          LineItemTypeT `json:"type"`                // Type of work performed
+	Subtype       string        `json:"subtype"`             // Subtype of work performed
+	Description   string        `json:"description"`         // Description of work performed
+	ProposalToken string        `json:"proposaltoken"`       // Link to politeia proposal that work is associated with
+	Hours         float64       `json:"hours"`               // Number of Hours
+	TotalCost     float64       `json:"totalcost"`           // Total cost of line item
+	Currency      string        `json:"currency,omitempty"`  // Currency of an expense or misc cost, empty for the default unit
+	StartDate     int64         `json:"startdate,omitempty"` // Unix timestamp of the first day worked (optional)
+	EndDate       int64         `json:"enddate,omitempty"`   // Unix timestamp of the last day worked (optional)
+	Tags          []string      `json:"tags,omitempty"`      // Labels such as a sprint or cost center (optional)
 }
 
 // UserInvoices is used to get all of the invoices by userID.
@@ -86,6 +86,14 @@ const (
 	// accepted when creating a new proposal
 	PolicyMaxMDSize = 512 * 1024
 
+	// PolicyMaxPDFs is the maximum number of PDF files accepted
+	// when creating a new invoice.  Proposals do not accept PDF files.
+	PolicyMaxPDFs = 1
+
+	// PolicyMaxPDFSize is the maximum PDF file size (in bytes)
+	// accepted when creating a new invoice
+	PolicyMaxPDFSize = 1024 * 1024
+
 	// PolicyMinPasswordLength is the minimum number of characters
 	// accepted for user passwords
 	PolicyMinPasswordLength = 8
@@ -130,6 +138,14 @@ const (
 	// csv line items
 	PolicyInvoiceLineItemCount = 6
 
+	// PolicyInvoiceMaxSubtypeLength is the maximum number of characters
+	// accepted for an invoice line item subtype
+	PolicyInvoiceMaxSubtypeLength = 50
+
+	// PolicyInvoiceMaxDescriptionLength is the maximum number of
+	// characters accepted for an invoice line item description
+	PolicyInvoiceMaxDescriptionLength = 500
+
 	// ProposalListPageSize is the maximum number of proposals returned
 	// for the routes that return lists of proposals
 	ProposalListPageSize = 20
@@ -209,6 +225,10 @@ const (
 	ErrorStatusInvalidInvoiceStatusTransition ErrorStatusT = 65
 	ErrorStatusReasonNotProvided              ErrorStatusT = 66
 	ErrorStatusInvoiceDuplicate               ErrorStatusT = 67
+	ErrorStatusMaxPDFsExceededPolicy          ErrorStatusT = 68
+	ErrorStatusMaxPDFSizeExceededPolicy       ErrorStatusT = 69
+	ErrorStatusInvoiceProxyNotAdmin           ErrorStatusT = 70
+	ErrorStatusInvoiceMonthNotAccepted        ErrorStatusT = 71
 
 	// Proposal state codes
 	//
@@ -365,6 +385,10 @@ var (
 		ErrorStatusReasonNotProvided:              "reason for action not provided",
 		ErrorStatusMalformedInvoiceFile:           "submitted invoice file is malformed",
 		ErrorStatusInvoiceDuplicate:               "submitted invoice is a duplicate of an existing invoice",
+		ErrorStatusMaxPDFsExceededPolicy:          "maximum PDF files exceeded",
+		ErrorStatusMaxPDFSizeExceededPolicy:       "maximum PDF file size exceeded",
+		ErrorStatusInvoiceProxyNotAdmin:           "only admins may submit an invoice on behalf of another user",
+		ErrorStatusInvoiceMonthNotAccepted:        "invoices are not accepted for the requested month",
 	}
 
 	// PropStatus converts propsal status codes to human readable text
@@ -847,6 +871,28 @@ type PolicyReply struct {
 	InvoiceCommentChar         rune     `json:"invoicecommentchar"`
 	InvoiceFieldDelimiterChar  rune     `json:"invoicefielddelimiterchar"`
 	InvoiceLineItemCount       uint     `json:"invoicelineitemcount"`
+	InvoiceMaxSubtypeLength    uint     `json:"invoicemaxsubtypelength"`
+	InvoiceMaxDescLength       uint     `json:"invoicemaxdesclength"`
+	MaxPDFs                    uint     `json:"maxpdfs,omitempty"`
+	MaxPDFSize                 uint     `json:"maxpdfsize,omitempty"`
+
+	// InvoiceLineItemSubtypes contains the allowed line item subtypes
+	// keyed by line item type name (labor, expense, misc).  It is
+	// omitted when the server does not restrict the subtypes.
+	InvoiceLineItemSubtypes map[string][]string `json:"invoicelineitemsubtypes,omitempty"`
+
+	// InvoiceWindow contains the range of months that invoices are
+	// accepted for.  It is omitted when the server accepts invoices for
+	// any month.
+	InvoiceWindow *InvoiceWindow `json:"invoicewindow,omitempty"`
+}
+
+// InvoiceWindow describes the range of months that invoices are accepted for
+// relative to the current month.  A window with both fields set to 0 only
+// accepts invoices for the current month.
+type InvoiceWindow struct {
+	MonthsBefore uint `json:"monthsbefore"` // Number of past months accepted
+	MonthsAfter  uint `json:"monthsafter"`  // Number of future months accepted
 }
 
 // VoteOption describes a single vote option.
@@ -1070,14 +1116,15 @@ type VoteStatus struct{}
 
 // VoteStatusReply describes the vote status for a given proposal
 type VoteStatusReply struct {
-	Token              string             `json:"token"`              // Censorship token
-	Status             PropVoteStatusT    `json:"status"`             // Vote status (finished, started, etc)
-	TotalVotes         uint64             `json:"totalvotes"`         // Proposal's total number of votes
-	OptionsResult      []VoteOptionResult `json:"optionsresult"`      // VoteOptionResult for each option
-	EndHeight          string             `json:"endheight"`          // Vote end height
-	NumOfEligibleVotes int                `json:"numofeligiblevotes"` // Total number of eligible votes
-	QuorumPercentage   uint32             `json:"quorumpercentage"`   // Percent of eligible votes required for quorum
-	PassPercentage     uint32             `json:"passpercentage"`     // Percent of total votes required to pass
+	Token              string             `json:"token"`               // Censorship token
+	Status             PropVoteStatusT    `json:"status"`              // Vote status (finished, started, etc)
+	TotalVotes         uint64             `json:"totalvotes"`          // Proposal's total number of votes
+	OptionsResult      []VoteOptionResult `json:"optionsresult"`       // VoteOptionResult for each option
+	EndHeight          string             `json:"endheight"`           // Vote end height
+	NumOfEligibleVotes int                `json:"numofeligiblevotes"`  // Total number of eligible votes
+	QuorumPercentage   uint32             `json:"quorumpercentage"`    // Percent of eligible votes required for quorum
+	PassPercentage     uint32             `json:"passpercentage"`      // Percent of total votes required to pass
+	BestBlock          string             `json:"bestblock,omitempty"` // Chain height when the status was fetched
 }
 
 // GetAllVoteStatus attempts to fetch the vote status of all public propsals
@@ -16,6 +16,7 @@ import (
 	"os"
 	"reflect"
 	"strings"
+	"time"
 
 	"github.com/decred/dcrwallet/rpc/walletrpc"
 	cms "github.com/decred/politeia/politeiawww/api/cms/v1"
@@ -41,6 +42,57 @@ type Client struct {
 	wallet walletrpc.WalletServiceClient
 }
 
+// ResponseError is returned when politeiawww responds with a status code
+// other than 200.
+type ResponseError struct {
+	StatusCode int           // HTTP status code
+	UserError  *v1.UserError // User error, if one was returned
+}
+
+// Error satisfies the error interface.
+func (e *ResponseError) Error() string {
+	if e.UserError != nil {
+		return fmt.Sprintf("%v, %v %v", e.StatusCode,
+			v1.ErrorStatus[e.UserError.ErrorCode],
+			strings.Join(e.UserError.ErrorContext, ", "))
+	}
+	return fmt.Sprintf("%v", e.StatusCode)
+}
+
+// timeoutError is returned in place of the underlying error of a request
+// that exceeded the request timeout.
+type timeoutError struct {
+	timeout time.Duration
+}
+
+// Error satisfies the error interface.
+func (e *timeoutError) Error() string {
+	return fmt.Sprintf("request timed out after %v; use --timeout to "+
+		"change the timeout", e.timeout)
+}
+
+// Timeout returns true so that the error is recognized as a timeout.
+func (e *timeoutError) Timeout() bool {
+	return true
+}
+
+// Temporary returns true so that the request can be retried.
+func (e *timeoutError) Temporary() bool {
+	return true
+}
+
+// do sends the passed in request.  A request that exceeds the request timeout
+// returns a url.Error that wraps a timeoutError.
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	r, err := c.http.Do(req)
+	if ue, ok := err.(*url.Error); ok && ue.Timeout() && c.cfg.Timeout > 0 {
+		ue.Err = &timeoutError{
+			timeout: c.cfg.Timeout,
+		}
+	}
+	return r, err
+}
+
 func prettyPrintJSON(v interface{}) error {
 	b, err := json.MarshalIndent(v, "", "  ")
 	if err != nil {
@@ -109,7 +161,7 @@ func (c *Client) makeRequest(method, route string, body interface{}) ([]byte, er
 	req.Header.Add(v1.CsrfToken, c.cfg.CSRF)
 
 	// Send request
-	r, err := c.http.Do(req)
+	r, err := c.do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -124,11 +176,15 @@ func (c *Client) makeRequest(method, route string, body interface{}) ([]byte, er
 		var ue v1.UserError
 		err = json.Unmarshal(responseBody, &ue)
 		if err == nil && ue.ErrorCode != 0 {
-			return nil, fmt.Errorf("%v, %v %v", r.StatusCode,
-				v1.ErrorStatus[ue.ErrorCode], strings.Join(ue.ErrorContext, ", "))
+			return nil, &ResponseError{
+				StatusCode: r.StatusCode,
+				UserError:  &ue,
+			}
 		}
 
-		return nil, fmt.Errorf("%v", r.StatusCode)
+		return nil, &ResponseError{
+			StatusCode: r.StatusCode,
+		}
 	}
 
 	// Print response details
@@ -157,7 +213,7 @@ func (c *Client) Version() (*v1.VersionReply, error) {
 	req.Header.Add(v1.CsrfToken, c.cfg.CSRF)
 
 	// Send request
-	r, err := c.http.Do(req)
+	r, err := c.do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -245,7 +301,7 @@ func (c *Client) Login(l *v1.Login) (*v1.LoginReply, error) {
 	req.Header.Add(v1.CsrfToken, c.cfg.CSRF)
 
 	// Send request
-	r, err := c.http.Do(req)
+	r, err := c.do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -310,7 +366,7 @@ func (c *Client) Logout() (*v1.LogoutReply, error) {
 	req.Header.Add(v1.CsrfToken, c.cfg.CSRF)
 
 	// Send request
-	r, err := c.http.Do(req)
+	r, err := c.do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -1517,6 +1573,7 @@ func New(cfg *config.Config) (*Client, error) {
 	httpClient := &http.Client{
 		Transport: tr,
 		Jar:       jar,
+		Timeout:   cfg.Timeout,
 	}
 
 	return &Client{
@@ -22,7 +22,7 @@ type AdminInvoicesCmd struct {
 // Execute executes the admin invoices command.
 func (cmd *AdminInvoicesCmd) Execute(args []string) error {
 	// Get server public key
-	vr, err := client.Version()
+	iv, err := newInvoiceVerifier()
 	if err != nil {
 		return err
 	}
@@ -40,7 +40,7 @@ func (cmd *AdminInvoicesCmd) Execute(args []string) error {
 
 	// Verify invoice censorship records
 	for _, p := range uir.Invoices {
-		err := verifyInvoice(p, vr.PubKey)
+		err := iv.verify(p)
 		if err != nil {
 			return fmt.Errorf("unable to verify invoice %v: %v",
 				p.CensorshipRecord.Token, err)
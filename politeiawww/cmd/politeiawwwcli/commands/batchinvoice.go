@@ -0,0 +1,181 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+
+	"github.com/decred/politeia/util"
+)
+
+// batchInvoiceFileRegexp matches the csv file names that the batch invoice
+// command submits.  The file name specifies the invoice year and month.
+var batchInvoiceFileRegexp = regexp.MustCompile(`^(\d{4})-(\d{2})\.csv(\.gz)?$`)
+
+// BatchInvoiceCmd submits a new invoice for each csv file in a directory.
+type BatchInvoiceCmd struct {
+	Args struct {
+		Dir string `positional-arg-name:"dir" required:"true"` // Directory of invoice csv files
+	} `positional-args:"true"`
+	Continue bool `long:"continue" optional:"true"` // Keep going after a failure
+	DryRun   bool `long:"dryrun" optional:"true"`   // Validate invoices without submitting
+
+	// The flags below are passed through to newinvoice for each
+	// invoice of the batch.
+	Identity        string `long:"identity" optional:"true"`              // Identity file used to sign
+	Delimiter       string `long:"delimiter" optional:"true"`             // Line item field delimiter
+	SkipHeader      bool   `long:"skip-header" optional:"true"`           // Ignore the first csv record
+	FieldMapping    string `long:"field-mapping" optional:"true"`         // Csv column of each line item field
+	LazyQuotes      bool   `long:"lazy-quotes" optional:"true"`           // Accept stray quotes in fields
+	Rate            uint   `long:"rate" optional:"true"`                  // Expected labor rate (atoms/hour)
+	Precision       *uint  `long:"precision" optional:"true"`             // Decimal places of hours and costs
+	MaxLineItems    uint   `long:"max-line-items" optional:"true"`        // Maximum number of line items
+	AllowDuplicates bool   `long:"allow-duplicates" optional:"true"`      // Warn on duplicate line items
+	StrictHours     bool   `long:"strict-hours" optional:"true"`          // Fail on implausible labor hours
+	NoTokenCheck    bool   `long:"no-token-format-check" optional:"true"` // Skip proposal token format check
+	VerifyTokens    bool   `long:"verify-tokens" optional:"true"`         // Warn on unknown or inactive proposals
+	StrictTokens    bool   `long:"strict-tokens" optional:"true"`         // Fail on unknown or inactive proposals
+	ValidateSubtype bool   `long:"validate-subtypes" optional:"true"`     // Validate subtypes against the server
+	Sort            bool   `long:"sort" optional:"true"`                  // Sort line items canonically
+	Attempts        uint   `long:"attempts" optional:"true"`              // Maximum submission attempts
+	Yes             bool   `long:"yes" optional:"true"`                   // Submit to mainnet without confirming
+}
+
+// newInvoiceCmd returns the new invoice command that submits the passed in
+// csv file for the passed in month and year using the batch flags.
+func (cmd *BatchInvoiceCmd) newInvoiceCmd(month, year, csvFile string) *NewInvoiceCmd {
+	ni := &NewInvoiceCmd{
+		DryRun:          cmd.DryRun,
+		Identity:        cmd.Identity,
+		Delimiter:       cmd.Delimiter,
+		SkipHeader:      cmd.SkipHeader,
+		FieldMapping:    cmd.FieldMapping,
+		LazyQuotes:      cmd.LazyQuotes,
+		Rate:            cmd.Rate,
+		Precision:       cmd.Precision,
+		MaxLineItems:    cmd.MaxLineItems,
+		AllowDuplicates: cmd.AllowDuplicates,
+		StrictHours:     cmd.StrictHours,
+		NoTokenCheck:    cmd.NoTokenCheck,
+		VerifyTokens:    cmd.VerifyTokens,
+		StrictTokens:    cmd.StrictTokens,
+		ValidateSubtype: cmd.ValidateSubtype,
+		Sort:            cmd.Sort,
+		Attempts:        cmd.Attempts,
+		Yes:             cmd.Yes,
+	}
+	ni.Args.Month = month
+	ni.Args.Year = year
+	ni.Args.CSV = csvFile
+	return ni
+}
+
+// Execute executes the batch invoice command.
+func (cmd *BatchInvoiceCmd) Execute(args []string) error {
+	dir := util.CleanAndExpandPath(cmd.Args.Dir)
+	fi, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("ReadDir %v: %v", dir, err)
+	}
+
+	var invoices []*NewInvoiceCmd
+	var names []string
+	for _, v := range fi {
+		m := batchInvoiceFileRegexp.FindStringSubmatch(v.Name())
+		if v.IsDir() || m == nil {
+			continue
+		}
+		invoices = append(invoices, cmd.newInvoiceCmd(m[2], m[1],
+			filepath.Join(dir, v.Name())))
+		names = append(names, v.Name())
+	}
+
+	// Submitting to a mainnet server is confirmed once for the whole
+	// batch instead of once per invoice.
+	if !cmd.DryRun && !cmd.Yes && len(invoices) > 0 {
+		iv, err := newInvoiceVerifier()
+		if err != nil {
+			return err
+		}
+		if !iv.version.TestNet {
+			err = confirmMainnetSubmit(fmt.Sprintf("%v invoices",
+				len(invoices)))
+			if err != nil {
+				return err
+			}
+		}
+		for _, v := range invoices {
+			v.Yes = true
+		}
+	}
+
+	var (
+		succeeded []string
+		failed    []string
+	)
+	for i, ni := range invoices {
+		err := ni.Execute(nil)
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%v: %v", names[i], err))
+			if !cmd.Continue {
+				break
+			}
+			continue
+		}
+		succeeded = append(succeeded, names[i])
+	}
+
+	// Print summary
+	fmt.Printf("Succeeded: %v\n", len(succeeded))
+	for _, v := range succeeded {
+		fmt.Printf("  %v\n", v)
+	}
+	fmt.Printf("Failed: %v\n", len(failed))
+	for _, v := range failed {
+		fmt.Printf("  %v\n", v)
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("%v invoice(s) failed", len(failed))
+	}
+
+	return nil
+}
+
+// batchInvoiceHelpMsg is the output of the help command when 'batchinvoice'
+// is specified.
+const batchInvoiceHelpMsg = `batchinvoice [flags] "dir"
+
+Submit a new invoice for each csv file in a directory. The csv files must be
+named using the invoice year and month, e.g. 2019-01.csv or 2019-01.csv.gz.
+Files are submitted in filename order. Other files are ignored.
+
+Arguments:
+1. dir               (string, required)   Directory of invoice csv files
+
+Flags:
+  --continue         (bool, optional)     Keep submitting invoices after a
+                                          failure
+  --dryrun           (bool, optional)     Validate and sign the invoices but do
+                                          not submit them
+  --yes              (bool, optional)     Submit to a mainnet server without
+                                          asking for confirmation. Without it
+                                          the batch is confirmed once.
+
+The --identity, --delimiter, --skip-header, --field-mapping, --lazy-quotes,
+--rate, --precision, --max-line-items, --allow-duplicates, --strict-hours,
+--no-token-format-check, --verify-tokens, --strict-tokens,
+--validate-subtypes, --sort and --attempts flags are applied to each invoice
+of the batch. See 'newinvoice' for their descriptions. The server policy is
+fetched once for the batch.
+
+Result:
+Succeeded: (int)  Number of invoices submitted
+  (string)        Filename
+Failed:    (int)  Number of invoices that failed
+  (string)        Filename: error`
@@ -0,0 +1,110 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+
+	cms "github.com/decred/politeia/politeiawww/api/cms/v1"
+)
+
+// CancelInvoiceCmd withdraws an invoice that has not been reviewed yet.
+type CancelInvoiceCmd struct {
+	Args struct {
+		Token  string `positional-arg-name:"token" required:"true"` // Invoice censorship token
+		Reason string `positional-arg-name:"reason"`                // Reason for withdrawing
+	} `positional-args:"true"`
+	Identity string `long:"identity" optional:"true"` // Identity file used to sign
+}
+
+// Execute executes the cancel invoice command.
+func (cmd *CancelInvoiceCmd) Execute(args []string) error {
+	// Load user identity
+	id, err := loadInvoiceIdentity(cmd.Identity)
+	if err != nil {
+		return err
+	}
+
+	// Verify that the invoice can still be withdrawn
+	idr, err := client.InvoiceDetails(cmd.Args.Token)
+	if err != nil {
+		return err
+	}
+	status := idr.Invoice.Status
+	if status == cms.InvoiceStatusWithdrawn {
+		return fmt.Errorf("invoice %v has already been withdrawn",
+			cmd.Args.Token)
+	}
+	err = validateInvoiceStatusTransition(status, cms.InvoiceStatusWithdrawn)
+	if err != nil {
+		return fmt.Errorf("invoice %v is %v and can no longer be withdrawn; "+
+			"only invoices that have not been reviewed by an admin can be "+
+			"withdrawn", cmd.Args.Token, invoiceStatuses[status])
+	}
+
+	// Setup request
+	sig := id.SignMessage([]byte(cmd.Args.Token +
+		strconv.Itoa(int(cms.InvoiceStatusWithdrawn)) + cmd.Args.Reason))
+
+	sis := &cms.SetInvoiceStatus{
+		Token:     cmd.Args.Token,
+		Status:    cms.InvoiceStatusWithdrawn,
+		Reason:    cmd.Args.Reason,
+		PublicKey: hex.EncodeToString(id.Public.Key[:]),
+		Signature: hex.EncodeToString(sig[:]),
+	}
+
+	// Print request details
+	err = printJSON(sis)
+	if err != nil {
+		return err
+	}
+
+	// Send request
+	sisr, err := client.SetInvoiceStatus(sis)
+	if err != nil {
+		return err
+	}
+
+	return printJSON(sisr)
+}
+
+// cancelInvoiceHelpMsg is the output of the help command when
+// 'cancelinvoice' is specified.
+const cancelInvoiceHelpMsg = `cancelinvoice [flags] "token" "reason"
+
+Withdraw a submitted invoice. Only the invoice author can withdraw an invoice
+and only while it has not been reviewed by an admin, i.e. while its status is
+new or updated. The invoice status is checked before the request is sent.
+
+Arguments:
+1. token      (string, required)   Invoice censorship token
+2. reason     (string, optional)   Reason for withdrawing the invoice
+
+Flags:
+  --identity         (string, optional)   Path of the identity file used to
+                                          sign the status change. Defaults to
+                                          the POLITEIAWWWCLI_IDENTITY
+                                          environment variable or the logged
+                                          in user's identity.
+
+Request:
+{
+  "token":           (string)          Censorship token
+  "status":          (InvoiceStatusT)  Invoice status code (8, withdrawn)
+  "reason":          (string)          Reason for withdrawing the invoice
+  "signature":       (string)          Signature of token+status+reason
+  "publickey":       (string)          Public key of the invoice author
+}
+
+Response:
+{
+  "invoice": {
+    "status":        (InvoiceStatusT)  Current status of invoice
+    ...
+  }
+}`
@@ -0,0 +1,57 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package commands
+
+import "fmt"
+
+// cmsWWWMode is the politeiawww mode that serves the CMS routes.
+const cmsWWWMode = "cmswww"
+
+// CMSVersionCmd prints the server version information that is relevant to
+// invoice submissions.
+type CMSVersionCmd struct{}
+
+// Execute executes the cms version command.
+func (cmd *CMSVersionCmd) Execute(args []string) error {
+	vr, err := client.Version()
+	if err != nil {
+		return err
+	}
+
+	if cfg.RawJSON {
+		return printJSON(vr)
+	}
+
+	fmt.Printf("Host           : %v\n", cfg.Host)
+	fmt.Printf("API version    : %v\n", vr.Version)
+	fmt.Printf("API route      : %v\n", vr.Route)
+	fmt.Printf("Mode           : %v\n", vr.Mode)
+	fmt.Printf("CMS enabled    : %v\n", vr.Mode == cmsWWWMode)
+	fmt.Printf("Testnet        : %v\n", vr.TestNet)
+	fmt.Printf("Server pubkey  : %v\n", vr.PubKey)
+	fmt.Printf("Client version : %v\n", cfg.Version)
+
+	return nil
+}
+
+// cmsVersionHelpMsg is the output of the help command when 'cmsversion' is
+// specified.
+const cmsVersionHelpMsg = `cmsversion
+
+Fetch the server version info and print it in a readable form along with
+whether the server is running in CMS mode. Include this output in bug reports
+about invoice submissions.
+
+Arguments: None
+
+Result:
+Host           (string)  politeiawww host
+API version    (uint)    politeiawww API version
+API route      (string)  Prefix of the API routes
+Mode           (string)  politeiawww mode (piwww or cmswww)
+CMS enabled    (bool)    Whether the server accepts invoices
+Testnet        (bool)    Whether testnet is being used
+Server pubkey  (string)  Server public key
+Client version (string)  politeiawwwcli version`
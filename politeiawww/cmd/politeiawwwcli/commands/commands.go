@@ -5,6 +5,7 @@
 package commands
 
 import (
+	"bufio"
 	"bytes"
 	"crypto/sha256"
 	"encoding/base64"
@@ -12,7 +13,18 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
 	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/agl/ed25519"
 	"github.com/decred/dcrd/chaincfg/chainhash"
@@ -56,61 +68,78 @@ var (
 
 // Cmds is used to represent all of the politeiawwwcli commands.
 type Cmds struct {
-	AdminInvoices      AdminInvoicesCmd      `command:"admininvoices" description:"(admin) get all invoices (optional by month/year and/or status)"`
-	ActiveVotes        ActiveVotesCmd        `command:"activevotes" description:"(public) get the proposals that are being voted on"`
-	AuthorizeVote      AuthorizeVoteCmd      `command:"authorizevote" description:"(user)   authorize a proposal vote (must be proposal author)"`
-	CensorComment      CensorCommentCmd      `command:"censorcomment" description:"(admin)  censor a proposal comment"`
-	ChangePassword     ChangePasswordCmd     `command:"changepassword" description:"(user)   change the password for the logged in user"`
-	ChangeUsername     ChangeUsernameCmd     `command:"changeusername" description:"(user)   change the username for the logged in user"`
-	EditInvoice        EditInvoiceCmd        `command:"editinvoice" description:"(user)    edit a invoice"`
-	EditProposal       EditProposalCmd       `command:"editproposal" description:"(user)   edit a proposal"`
-	ManageUser         ManageUserCmd         `command:"manageuser" description:"(admin)  edit certain properties of the specified user"`
-	EditUser           EditUserCmd           `command:"edituser" description:"(user)   edit the  preferences of the logged in user"`
-	Help               HelpCmd               `command:"help" description:"         print a detailed help message for a specific command"`
-	Inventory          InventoryCmd          `command:"inventory" description:"(public) get the proposals that are being voted on"`
-	InviteNewUser      InviteNewUserCmd      `command:"invite" description:"(admin)  invite a new user"`
-	InvoiceDetails     InvoiceDetailsCmd     `command:"invoicedetails" description:"(public) get the details of a proposal"`
-	LikeComment        LikeCommentCmd        `command:"likecomment" description:"(user)   upvote/downvote a comment"`
-	Login              LoginCmd              `command:"login" description:"(public) login to Politeia"`
-	Logout             LogoutCmd             `command:"logout" description:"(public) logout of Politeia"`
-	Me                 MeCmd                 `command:"me" description:"(user)   get user details for the logged in user"`
-	NewInvoice         NewInvoiceCmd         `command:"newinvoice" description:"(user)   create a new invoice"`
-	NewProposal        NewProposalCmd        `command:"newproposal" description:"(user)   create a new proposal"`
-	NewComment         NewCommentCmd         `command:"newcomment" description:"(user)   create a new proposal comment"`
-	NewUser            NewUserCmd            `command:"newuser" description:"(public) create a new user"`
-	Policy             PolicyCmd             `command:"policy" description:"(public) get the server policy"`
-	ProposalComments   ProposalCommentsCmd   `command:"proposalcomments" description:"(public) get the comments for a proposal"`
-	ProposalDetails    ProposalDetailsCmd    `command:"proposaldetails" description:"(public) get the details of a proposal"`
-	ProposalPaywall    ProposalPaywallCmd    `command:"proposalpaywall" description:"(user)   get proposal paywall details for the logged in user"`
-	ProposalStats      ProposalStatsCmd      `command:"proposalstats" description:"(public) get statistics on the proposal inventory"`
-	UnvettedProposals  UnvettedProposalsCmd  `command:"unvettedproposals" description:"(admin)  get a page of unvetted proposals"`
-	VettedProposals    VettedProposalsCmd    `command:"vettedproposals" description:"(public) get a page of vetted proposals"`
-	RegisterUser       RegisterUserCmd       `command:"register" description:"(public) register an invited user to cms"`
-	RescanUserPayments RescanUserPaymentsCmd `command:"rescanuserpayments" description:"(admin)  rescan a user's payments to check for missed payments"`
-	ResendVerification ResendVerificationCmd `command:"resendverification" description:"(public) resend the user verification email"`
-	ResetPassword      ResetPasswordCmd      `command:"resetpassword" description:"(public) reset the password for a user that is not logged in"`
-	Secret             SecretCmd             `command:"secret" description:"(user)   ping politeiawww"`
-	SendFaucetTx       SendFaucetTxCmd       `command:"sendfaucettx" description:"         send a DCR transaction using the Decred testnet faucet"`
-	SetInvoiceStatus   SetInvoiceStatusCmd   `command:"setinvoicestatus" description:"(admin)  set the status of an invoice"`
-	SetProposalStatus  SetProposalStatusCmd  `command:"setproposalstatus" description:"(admin)  set the status of a proposal"`
-	StartVote          StartVoteCmd          `command:"startvote" description:"(admin)  start the voting period on a proposal"`
-	Subscribe          SubscribeCmd          `command:"subscribe" description:"(public) subscribe to all websocket commands and do not exit tool"`
-	Tally              TallyCmd              `command:"tally" description:"(public) get the vote tally for a proposal"`
-	TestRun            TestRunCmd            `command:"testrun" description:"         run a series of tests on the politeiawww routes (dev use only)"`
-	UpdateUserKey      UpdateUserKeyCmd      `command:"updateuserkey" description:"(user)   generate a new identity for the logged in user"`
-	UserDetails        UserDetailsCmd        `command:"userdetails" description:"(public) get the details of a user profile"`
-	UserLikeComments   UserLikeCommentsCmd   `command:"userlikecomments" description:"(user)   get the logged in user's comment upvotes/downvotes for a proposal"`
-	UserPendingPayment UserPendingPaymentCmd `command:"userpendingpayment" description:"(user)   get details for a pending payment for the logged in user"`
-	UserInvoices       UserInvoicesCmd       `command:"userinvoices" description:"(user) get all invoices submitted by a specific user"`
-	UserProposals      UserProposalsCmd      `command:"userproposals" description:"(public) get all proposals submitted by a specific user"`
-	Users              UsersCmd              `command:"users" description:"(admin)  get a list of users"`
-	VerifyUserEmail    VerifyUserEmailCmd    `command:"verifyuseremail" description:"(public) verify a user's email address"`
-	VerifyUserPayment  VerifyUserPaymentCmd  `command:"verifyuserpayment" description:"(user)   check if the logged in user has paid their user registration fee"`
-	Version            VersionCmd            `command:"version" description:"(public) get server info and CSRF token"`
-	Vote               VoteCmd               `command:"vote" description:"(public) cast votes for a proposal"`
-	VoteResults        VoteResultsCmd        `command:"voteresults" description:"(public) get vote results for a proposal"`
-	VoteStatus         VoteStatusCmd         `command:"votestatus" description:"(public) get the vote status of a proposal"`
-	VoteStatuses       VoteStatusesCmd       `command:"votestatuses" description:"(public) get the vote status for all public proposals"`
+	AdminInvoices         AdminInvoicesCmd         `command:"admininvoices" description:"(admin) get all invoices (optional by month/year and/or status)"`
+	ActiveVotes           ActiveVotesCmd           `command:"activevotes" description:"(public) get the proposals that are being voted on"`
+	AuthorizeVote         AuthorizeVoteCmd         `command:"authorizevote" description:"(user)   authorize a proposal vote (must be proposal author)"`
+	BatchInvoice          BatchInvoiceCmd          `command:"batchinvoice" description:"(user)   submit a new invoice for each csv file in a directory"`
+	CancelInvoice         CancelInvoiceCmd         `command:"cancelinvoice" description:"(user)   withdraw an invoice that has not been reviewed"`
+	CensorComment         CensorCommentCmd         `command:"censorcomment" description:"(admin)  censor a proposal comment"`
+	ChangePassword        ChangePasswordCmd        `command:"changepassword" description:"(user)   change the password for the logged in user"`
+	ChangeUsername        ChangeUsernameCmd        `command:"changeusername" description:"(user)   change the username for the logged in user"`
+	CMSVersion            CMSVersionCmd            `command:"cmsversion" description:"(public) get server version info and whether cms is enabled"`
+	CSVTemplate           CSVTemplateCmd           `command:"csvtemplate" description:"         print an example invoice csv"`
+	DiffInvoice           DiffInvoiceCmd           `command:"diffinvoice" description:"         compare the line items of two invoices"`
+	EditInvoice           EditInvoiceCmd           `command:"editinvoice" description:"(user)    edit a invoice"`
+	EditProposal          EditProposalCmd          `command:"editproposal" description:"(user)   edit a proposal"`
+	ManageUser            ManageUserCmd            `command:"manageuser" description:"(admin)  edit certain properties of the specified user"`
+	EditUser              EditUserCmd              `command:"edituser" description:"(user)   edit the  preferences of the logged in user"`
+	EstimateInvoice       EstimateInvoiceCmd       `command:"estimateinvoice" description:"         estimate the DCR payout of an invoice csv"`
+	ExportInvoice         ExportInvoiceCmd         `command:"exportinvoice" description:"         export the line items of an invoice to csv"`
+	GetInvoiceAttachments GetInvoiceAttachmentsCmd `command:"getinvoiceattachments" description:"(public) download the attachments of an invoice"`
+	Help                  HelpCmd                  `command:"help" description:"         print a detailed help message for a specific command"`
+	Inventory             InventoryCmd             `command:"inventory" description:"(public) get the proposals that are being voted on"`
+	InviteNewUser         InviteNewUserCmd         `command:"invite" description:"(admin)  invite a new user"`
+	InvoiceDetails        InvoiceDetailsCmd        `command:"invoicedetails" description:"(public) get the details of a proposal"`
+	InvoicePayment        InvoicePaymentCmd        `command:"invoicepayment" description:"(public) print the payment status of an invoice"`
+	InvoicePolicy         InvoicePolicyCmd         `command:"invoicepolicy" description:"(public) get the server invoice policy"`
+	InvoiceReport         InvoiceReportCmd         `command:"invoicereport" description:"(user)   print invoice totals by type, proposal and month"`
+	InvoiceTimeline       InvoiceTimelineCmd       `command:"invoicetimeline" description:"(public) print the status changes of an invoice"`
+	LikeComment           LikeCommentCmd           `command:"likecomment" description:"(user)   upvote/downvote a comment"`
+	ListContractors       ListContractorsCmd       `command:"listcontractors" description:"(admin)  list the registered contractors"`
+	ListInvoices          ListInvoicesCmd          `command:"listinvoices" description:"(user)   list the invoices of the logged in user"`
+	Login                 LoginCmd                 `command:"login" description:"(public) login to Politeia"`
+	Logout                LogoutCmd                `command:"logout" description:"(public) logout of Politeia"`
+	Me                    MeCmd                    `command:"me" description:"(user)   get user details for the logged in user"`
+	NewInvoice            NewInvoiceCmd            `command:"newinvoice" description:"(user)   create a new invoice"`
+	NewProposal           NewProposalCmd           `command:"newproposal" description:"(user)   create a new proposal"`
+	NewComment            NewCommentCmd            `command:"newcomment" description:"(user)   create a new proposal comment"`
+	NewUser               NewUserCmd               `command:"newuser" description:"(public) create a new user"`
+	Policy                PolicyCmd                `command:"policy" description:"(public) get the server policy"`
+	ProposalComments      ProposalCommentsCmd      `command:"proposalcomments" description:"(public) get the comments for a proposal"`
+	ProposalDetails       ProposalDetailsCmd       `command:"proposaldetails" description:"(public) get the details of a proposal"`
+	ProposalPaywall       ProposalPaywallCmd       `command:"proposalpaywall" description:"(user)   get proposal paywall details for the logged in user"`
+	ProposalStats         ProposalStatsCmd         `command:"proposalstats" description:"(public) get statistics on the proposal inventory"`
+	UnvettedProposals     UnvettedProposalsCmd     `command:"unvettedproposals" description:"(admin)  get a page of unvetted proposals"`
+	VettedProposals       VettedProposalsCmd       `command:"vettedproposals" description:"(public) get a page of vetted proposals"`
+	RegisterUser          RegisterUserCmd          `command:"register" description:"(public) register an invited user to cms"`
+	RescanUserPayments    RescanUserPaymentsCmd    `command:"rescanuserpayments" description:"(admin)  rescan a user's payments to check for missed payments"`
+	ResendVerification    ResendVerificationCmd    `command:"resendverification" description:"(public) resend the user verification email"`
+	ResetPassword         ResetPasswordCmd         `command:"resetpassword" description:"(public) reset the password for a user that is not logged in"`
+	Secret                SecretCmd                `command:"secret" description:"(user)   ping politeiawww"`
+	SendFaucetTx          SendFaucetTxCmd          `command:"sendfaucettx" description:"         send a DCR transaction using the Decred testnet faucet"`
+	SetInvoiceStatus      SetInvoiceStatusCmd      `command:"setinvoicestatus" description:"(admin)  set the status of an invoice"`
+	SetProposalStatus     SetProposalStatusCmd     `command:"setproposalstatus" description:"(admin)  set the status of a proposal"`
+	StartVote             StartVoteCmd             `command:"startvote" description:"(admin)  start the voting period on a proposal"`
+	Subscribe             SubscribeCmd             `command:"subscribe" description:"(public) subscribe to all websocket commands and do not exit tool"`
+	Tally                 TallyCmd                 `command:"tally" description:"(public) get the vote tally for a proposal"`
+	TestRun               TestRunCmd               `command:"testrun" description:"         run a series of tests on the politeiawww routes (dev use only)"`
+	UpdateUserKey         UpdateUserKeyCmd         `command:"updateuserkey" description:"(user)   generate a new identity for the logged in user"`
+	UserDetails           UserDetailsCmd           `command:"userdetails" description:"(public) get the details of a user profile"`
+	UserLikeComments      UserLikeCommentsCmd      `command:"userlikecomments" description:"(user)   get the logged in user's comment upvotes/downvotes for a proposal"`
+	UserPendingPayment    UserPendingPaymentCmd    `command:"userpendingpayment" description:"(user)   get details for a pending payment for the logged in user"`
+	UserInvoices          UserInvoicesCmd          `command:"userinvoices" description:"(user) get all invoices submitted by a specific user"`
+	UserProposals         UserProposalsCmd         `command:"userproposals" description:"(public) get all proposals submitted by a specific user"`
+	Users                 UsersCmd                 `command:"users" description:"(admin)  get a list of users"`
+	VerifyFiles           VerifyFilesCmd           `command:"verifyfiles" description:"(public) verify the file digests of an invoice or proposal"`
+	VerifyInvoice         VerifyInvoiceCmd         `command:"verifyinvoice" description:"         verify the signature of a locally saved invoice"`
+	VerifyInvoices        VerifyInvoicesCmd        `command:"verifyinvoices" description:"         verify a directory of locally saved invoices"`
+	VerifyUserEmail       VerifyUserEmailCmd       `command:"verifyuseremail" description:"(public) verify a user's email address"`
+	VerifyUserPayment     VerifyUserPaymentCmd     `command:"verifyuserpayment" description:"(user)   check if the logged in user has paid their user registration fee"`
+	Version               VersionCmd               `command:"version" description:"(public) get server info and CSRF token"`
+	Vote                  VoteCmd                  `command:"vote" description:"(public) cast votes for a proposal"`
+	VoteResults           VoteResultsCmd           `command:"voteresults" description:"(public) get the vote results summary for a proposal"`
+	VoteStatus            VoteStatusCmd            `command:"votestatus" description:"(public) get the vote status of a proposal"`
+	VoteStatuses          VoteStatusesCmd          `command:"votestatuses" description:"(public) get the vote status for all public proposals"`
 }
 
 // SetConfig sets the global config variable.
@@ -131,7 +160,7 @@ func printJSON(body interface{}) error {
 		// Keep quiet
 	case cfg.Verbose:
 		// Verbose printing is handled in the client
-	case cfg.RawJSON:
+	case (cfg.RawJSON || cfg.Compact) && !cfg.Pretty:
 		// Print raw JSON with no formatting
 		b, err := json.Marshal(body)
 		if err != nil {
@@ -150,6 +179,140 @@ func printJSON(body interface{}) error {
 	return nil
 }
 
+// isTransientError returns whether the passed in request error is likely to
+// be temporary, i.e. a network error or a 5xx server response, and the
+// request can be retried.  Validation and authentication errors are
+// permanent.
+func isTransientError(err error) bool {
+	switch e := err.(type) {
+	case *wwwclient.ResponseError:
+		return e.StatusCode >= http.StatusInternalServerError
+	case *url.Error:
+		if e.Timeout() {
+			return true
+		}
+		_, ok := e.Err.(*net.OpError)
+		return ok
+	}
+	return false
+}
+
+// retryInitialDelay is the delay before the first retry of a request that
+// failed with a transient error.
+const retryInitialDelay = time.Second
+
+// retryTransient calls f until it succeeds, returns a permanent error, has
+// been called the specified number of attempts or the timeout has elapsed.
+// The delay between attempts starts at one second and doubles after each
+// attempt.  The number of attempts that were made is returned.
+func retryTransient(attempts int, timeout time.Duration, f func() error) (int, error) {
+	deadline := time.Now().Add(timeout)
+	delay := retryInitialDelay
+	var i int
+	for {
+		i++
+		err := f()
+		if err == nil || !isTransientError(err) || i >= attempts ||
+			time.Now().Add(delay).After(deadline) {
+			return i, err
+		}
+		fmt.Fprintf(os.Stderr, "Attempt %v/%v failed: %v; retrying in %v\n",
+			i, attempts, err, delay)
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// jsonLogger writes structured log lines, one JSON object per line, that
+// describe the steps of a command.  Log lines are written to stderr so that
+// the regular output is not affected.  Nothing is logged when it is
+// disabled.
+type jsonLogger struct {
+	enabled bool
+	w       io.Writer
+}
+
+// newJSONLogger returns a jsonLogger that writes to stderr if enabled is
+// true.
+func newJSONLogger(enabled bool) *jsonLogger {
+	return &jsonLogger{
+		enabled: enabled,
+		w:       os.Stderr,
+	}
+}
+
+// log writes a log line for the passed in step.  The log line contains the
+// time, the step and the passed in fields.
+func (l *jsonLogger) log(step string, fields map[string]interface{}) {
+	if !l.enabled {
+		return
+	}
+	entry := make(map[string]interface{}, len(fields)+2)
+	for k, v := range fields {
+		entry[k] = v
+	}
+	entry["time"] = time.Now().UTC().Format(time.RFC3339Nano)
+	entry["step"] = step
+
+	b, err := json.Marshal(entry)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: unable to log %v: %v\n", step, err)
+		return
+	}
+	fmt.Fprintf(l.w, "%s\n", b)
+}
+
+// printProgress prints a progress message for long running commands.  The
+// message is suppressed when the output is silenced or is raw JSON.
+func printProgress(format string, args ...interface{}) {
+	if cfg.Silent || cfg.RawJSON {
+		return
+	}
+	fmt.Printf(format, args...)
+}
+
+// promptConfirm prints the passed in question and reads a yes or no answer
+// from stdin.  Anything other than y or yes is treated as no.
+func promptConfirm(question string) (bool, error) {
+	fmt.Printf("%v [y/N]: ", question)
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return false, err
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true, nil
+	}
+	return false, nil
+}
+
+// confirmMainnetSubmit asks for confirmation of the submission of the passed
+// in invoices (e.g. "invoice") to a mainnet server.  The submission cannot be
+// confirmed when stdin is not a terminal, in which case --yes must be used.
+func confirmMainnetSubmit(invoices string) error {
+	if !terminal.IsTerminal(int(os.Stdin.Fd())) {
+		return fmt.Errorf("submitting to the mainnet server %v must be "+
+			"confirmed; use --yes to submit without confirming", cfg.Host)
+	}
+	ok, err := promptConfirm(fmt.Sprintf("Submit %v to the mainnet "+
+		"server %v?", invoices, cfg.Host))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%v not submitted", invoices)
+	}
+	return nil
+}
+
+// networkName returns the name of the network that a server is running on.
+func networkName(testNet bool) string {
+	if testNet {
+		return "testnet"
+	}
+	return "mainnet"
+}
+
 // PromptPassphrase is used to prompt the user for the private passphrase to
 // their wallet.
 func promptPassphrase() ([]byte, error) {
@@ -198,6 +361,259 @@ func newIdentity() (*identity.FullIdentity, error) {
 	return id, nil
 }
 
+// identityEnvVar is the environment variable that can be used to specify the
+// path of the identity file used to sign invoices.
+const identityEnvVar = "POLITEIAWWWCLI_IDENTITY"
+
+// loadInvoiceIdentity returns the identity that is used to sign invoices.  The
+// identity is loaded from the passed in file path if one is provided, from
+// the file specified by the identity environment variable if it is set, or
+// is the identity of the logged in user otherwise.  The identity is validated
+// before it is returned.
+func loadInvoiceIdentity(path string) (*identity.FullIdentity, error) {
+	if path == "" {
+		path = os.Getenv(identityEnvVar)
+	}
+	if path == "" {
+		if cfg.Identity == nil {
+			return nil, errUserIdentityNotFound
+		}
+		err := validateIdentity(cfg.Identity)
+		if err != nil {
+			return nil, identityError(fmt.Errorf("identity of the logged "+
+				"in user: %v; use updateuserkey to generate a new identity",
+				err))
+		}
+		return cfg.Identity, nil
+	}
+
+	path = util.CleanAndExpandPath(path)
+	id, err := identity.LoadFullIdentity(path)
+	if err != nil {
+		return nil, identityError(fmt.Errorf("load identity %v: %v", path,
+			err))
+	}
+	err = validateIdentity(id)
+	if err != nil {
+		return nil, identityError(fmt.Errorf("identity %v: %v", path, err))
+	}
+
+	return id, nil
+}
+
+// validateIdentity verifies that the passed in identity is a well-formed
+// ed25519 key pair.  The identity file stores the keys as JSON arrays, which
+// are zero padded when the file is truncated, so the keys are checked for
+// missing bytes as well.
+func validateIdentity(id *identity.FullIdentity) error {
+	var zero [identity.PublicKeySize]byte
+	if bytes.Equal(id.Public.Key[:], zero[:]) {
+		return fmt.Errorf("public key is missing")
+	}
+	pub := id.PrivateKey[identity.PrivateKeySize-identity.PublicKeySize:]
+	if bytes.Equal(id.PrivateKey[:identity.PublicKeySize], zero[:]) ||
+		bytes.Equal(pub, zero[:]) {
+		return fmt.Errorf("private key is missing or truncated")
+	}
+
+	// The ed25519 private key contains the public key in its last
+	// 32 bytes.  Make sure they match and that the key pair produces
+	// valid signatures.
+	if !bytes.Equal(pub, id.Public.Key[:]) {
+		return fmt.Errorf("public key does not match private key")
+	}
+	msg := []byte("politeiawwwcli identity check")
+	if !id.Public.VerifyMessage(msg, id.SignMessage(msg)) {
+		return fmt.Errorf("invalid key pair")
+	}
+
+	return nil
+}
+
+// newFile converts the passed in payload into a File with the specified name.
+func newFile(name string, payload []byte) v1.File {
+	return v1.File{
+		Name:    name,
+		MIME:    mime.DetectMimeType(payload),
+		Digest:  hex.EncodeToString(util.Digest(payload)),
+		Payload: base64.StdEncoding.EncodeToString(payload),
+	}
+}
+
+const (
+	// attachmentDownloadTimeout is the maximum time that downloading
+	// an attachment that is referenced by URL may take.
+	attachmentDownloadTimeout = 30 * time.Second
+
+	// attachmentDownloadMaxSize is the maximum size of an attachment
+	// that is referenced by URL, which is the size of the largest
+	// attachment type.
+	attachmentDownloadMaxSize = v1.PolicyMaxPDFSize
+
+	// attachmentDownloadMaxRedirects is the maximum number of redirects
+	// that are followed when an attachment is downloaded.
+	attachmentDownloadMaxRedirects = 10
+)
+
+// checkAttachmentRedirect is the redirect policy of attachment downloads.
+// A https URL must not be redirected to a plain http URL since that would
+// bypass the refusal of plain http attachments.
+func checkAttachmentRedirect(req *http.Request, via []*http.Request) error {
+	if req.URL.Scheme != "https" {
+		return fmt.Errorf("redirect to %v refused: only https URLs are "+
+			"accepted", req.URL)
+	}
+	if len(via) >= attachmentDownloadMaxRedirects {
+		return fmt.Errorf("stopped after %v redirects",
+			attachmentDownloadMaxRedirects)
+	}
+	return nil
+}
+
+// readAttachment returns the filename and the contents of the passed in
+// attachment.  The attachment is either a local file or a https URL which is
+// downloaded.  Plain http URLs are refused.
+func readAttachment(file string) (string, []byte, error) {
+	switch {
+	case strings.HasPrefix(file, "http://"):
+		return "", nil, fmt.Errorf("attachment %v: only https URLs are "+
+			"accepted", file)
+	case !strings.HasPrefix(file, "https://"):
+		path := util.CleanAndExpandPath(file)
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return "", nil, fmt.Errorf("ReadFile %v: %v", path, err)
+		}
+		return filepath.Base(file), b, nil
+	}
+
+	u, err := url.Parse(file)
+	if err != nil {
+		return "", nil, fmt.Errorf("attachment %v: %v", file, err)
+	}
+	name := path.Base(u.Path)
+	if name == "/" || name == "." {
+		return "", nil, fmt.Errorf("attachment %v: URL does not contain a "+
+			"filename", file)
+	}
+
+	c := &http.Client{
+		Timeout:       attachmentDownloadTimeout,
+		CheckRedirect: checkAttachmentRedirect,
+	}
+	resp, err := c.Get(file)
+	if err != nil {
+		return "", nil, fmt.Errorf("download %v: %v", file, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", nil, fmt.Errorf("download %v: %v", file, resp.Status)
+	}
+
+	// Read one byte more than the maximum to detect attachments
+	// that are too large without reading all of them.
+	b, err := ioutil.ReadAll(io.LimitReader(resp.Body,
+		attachmentDownloadMaxSize+1))
+	if err != nil {
+		return "", nil, fmt.Errorf("download %v: %v", file, err)
+	}
+	if len(b) > attachmentDownloadMaxSize {
+		return "", nil, fmt.Errorf("download %v: attachment exceeds the "+
+			"maximum size of %v bytes", file, attachmentDownloadMaxSize)
+	}
+
+	return name, b, nil
+}
+
+// readAttachments reads the passed in attachment files into memory and
+// converts them to type File using a bounded pool of workers.  The returned
+// files are in the same order as the passed in file paths.
+func readAttachments(attachmentFiles []string) ([]v1.File, error) {
+	files := make([]v1.File, len(attachmentFiles))
+	errs := make([]error, len(attachmentFiles))
+
+	workers := runtime.NumCPU()
+	if workers > len(attachmentFiles) {
+		workers = len(attachmentFiles)
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				name, attachment, err := readAttachment(attachmentFiles[j])
+				if err != nil {
+					errs[j] = err
+					continue
+				}
+				printProgress("Reading attachment %v/%v: %v (%vKB)\n", j+1,
+					len(attachmentFiles), name, len(attachment)/1024)
+
+				files[j] = newFile(name, attachment)
+			}
+		}()
+	}
+	for i := range attachmentFiles {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return files, nil
+}
+
+// signFiles signs the merkle root of the passed in files using the passed
+// in identity and returns the hex encoded signature.  The signature is
+// verified locally so that a corrupted identity is caught before the
+// signature is sent to the server.
+func signFiles(files []v1.File, id *identity.FullIdentity) (string, error) {
+	sig, err := signedMerkleRoot(files, id)
+	if err != nil {
+		return "", identityError(fmt.Errorf("SignMerkleRoot: %v", err))
+	}
+	_, err = verifyMerkleSignature(files,
+		hex.EncodeToString(id.Public.Key[:]), sig)
+	if err != nil {
+		return "", identityError(fmt.Errorf("local signature self-check "+
+			"failed: %v", err))
+	}
+	return sig, nil
+}
+
+// verifyMerkleSignature computes the merkle root of the passed in files and
+// verifies that the signature is a valid signature of the merkle root for the
+// passed in public key.  The computed merkle root is returned even when the
+// signature is invalid.
+func verifyMerkleSignature(files []v1.File, publicKey, signature string) (string, error) {
+	mr, err := merkleRoot(files)
+	if err != nil {
+		return "", err
+	}
+
+	id, err := util.IdentityFromString(publicKey)
+	if err != nil {
+		return mr, err
+	}
+	sig, err := util.ConvertSignature(signature)
+	if err != nil {
+		return mr, err
+	}
+	if !id.VerifyMessage([]byte(mr), sig) {
+		return mr, fmt.Errorf("could not verify invoice signature")
+	}
+
+	return mr, nil
+}
+
 // merkleRoot converts the passed in list of files into SHA256 digests then
 // calculates and returns the merkle root of the digests.
 func merkleRoot(files []v1.File) (string, error) {
@@ -248,6 +664,115 @@ func signedMerkleRoot(files []v1.File, id *identity.FullIdentity) (string, error
 	return hex.EncodeToString(sig[:]), nil
 }
 
+// versionCacheTTL is the time that a cached version reply, and the server
+// public key that it contains, is reused before it is fetched again.
+const versionCacheTTL = 10 * time.Minute
+
+// invoiceVerifier verifies invoice censorship records against the server
+// public key.  The server version reply that contains the public key is
+// cached on disk between commands so that scripted bulk operations don't
+// request it for every command.
+type invoiceVerifier struct {
+	version *v1.VersionReply // Server version reply
+	cached  bool             // Whether the version was loaded from the cache
+}
+
+// newInvoiceVerifier returns an invoiceVerifier that uses the cached server
+// version if it has not expired, or fetches and caches it otherwise.
+func newInvoiceVerifier() (*invoiceVerifier, error) {
+	vr, err := cfg.LoadVersion(versionCacheTTL)
+	if err != nil {
+		return nil, err
+	}
+	if vr != nil {
+		return &invoiceVerifier{
+			version: vr,
+			cached:  true,
+		}, nil
+	}
+
+	iv := &invoiceVerifier{}
+	err = iv.refresh()
+	if err != nil {
+		return nil, err
+	}
+	return iv, nil
+}
+
+// refresh drops the cached server version and replaces it with a freshly
+// fetched one.
+func (iv *invoiceVerifier) refresh() error {
+	err := cfg.RemoveVersion()
+	if err != nil {
+		return err
+	}
+	vr, err := client.Version()
+	if err != nil {
+		return err
+	}
+	err = cfg.SaveVersion(vr)
+	if err != nil {
+		return err
+	}
+	iv.version = vr
+	iv.cached = false
+	return nil
+}
+
+// pubKey returns the server public key that invoices are verified against.
+func (iv *invoiceVerifier) pubKey() string {
+	return iv.version.PubKey
+}
+
+// verify verifies the passed in invoice.  If verification fails using a
+// cached server public key, the cache is invalidated and the invoice is
+// verified again using a freshly fetched public key, e.g. in case the server
+// key was rotated.
+func (iv *invoiceVerifier) verify(ir cms.InvoiceRecord) error {
+	err := verifyInvoice(ir, iv.pubKey())
+	if err == nil || !iv.cached {
+		return err
+	}
+
+	err = iv.refresh()
+	if err != nil {
+		return err
+	}
+	return verifyInvoice(ir, iv.pubKey())
+}
+
+// serverPublicKey returns the server public key that censorship records are
+// verified against.  The supplied key is either a hex encoded public key or
+// the path to a file that contains one.  The key is fetched from the server
+// if no key is supplied, unless offline is set.
+func serverPublicKey(supplied string, offline bool) (string, error) {
+	if supplied == "" {
+		if offline {
+			return "", fmt.Errorf("a server public key is required to " +
+				"verify a censorship record offline; use --server-pubkey")
+		}
+		vr, err := client.Version()
+		if err != nil {
+			return "", err
+		}
+		return vr.PubKey, nil
+	}
+
+	key := supplied
+	path := util.CleanAndExpandPath(supplied)
+	if _, err := os.Stat(path); err == nil {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("ReadFile %v: %v", path, err)
+		}
+		key = strings.TrimSpace(string(b))
+	}
+	if _, err := util.IdentityFromString(key); err != nil {
+		return "", fmt.Errorf("invalid server public key: %v", err)
+	}
+	return key, nil
+}
+
 // verifyProposal verifies a proposal's merkle root, author signature, and
 // censorship record.
 func verifyProposal(p v1.ProposalRecord, serverPubKey string) error {
@@ -336,6 +861,42 @@ func verifyInvoice(p cms.InvoiceRecord, serverPubKey string) error {
 	return nil
 }
 
+// verifyComment verifies a comment's author signature and the server receipt,
+// which is the server signature of the author signature.
+func verifyComment(c v1.Comment, serverPubKey string) error {
+	// Verify comment signature.  The server sets the parent ID of a
+	// top-level comment to 0, but the comment may have been signed
+	// with an empty parent ID.
+	pid, err := util.IdentityFromString(c.PublicKey)
+	if err != nil {
+		return err
+	}
+	sig, err := util.ConvertSignature(c.Signature)
+	if err != nil {
+		return err
+	}
+	msg := []byte(c.Token + c.ParentID + c.Comment)
+	if !pid.VerifyMessage(msg, sig) && !(c.ParentID == "0" &&
+		pid.VerifyMessage([]byte(c.Token+c.Comment), sig)) {
+		return fmt.Errorf("could not verify comment signature")
+	}
+
+	// Verify receipt
+	id, err := util.IdentityFromString(serverPubKey)
+	if err != nil {
+		return err
+	}
+	receipt, err := util.ConvertSignature(c.Receipt)
+	if err != nil {
+		return err
+	}
+	if !id.VerifyMessage([]byte(c.Signature), receipt) {
+		return fmt.Errorf("could not verify comment receipt")
+	}
+
+	return nil
+}
+
 // convertTicketHashes converts a slice of hexadecimal ticket hashes into
 // a slice of byte slices.
 func convertTicketHashes(h []string) ([][]byte, error) {
@@ -364,10 +925,6 @@ func createMDFile() (*v1.File, error) {
 		b.WriteString(base64.StdEncoding.EncodeToString(r) + "\n")
 	}
 
-	return &v1.File{
-		Name:    "index.md",
-		MIME:    mime.DetectMimeType(b.Bytes()),
-		Digest:  hex.EncodeToString(util.Digest(b.Bytes())),
-		Payload: base64.StdEncoding.EncodeToString(b.Bytes()),
-	}, nil
+	f := newFile("index.md", b.Bytes())
+	return &f, nil
 }
@@ -0,0 +1,130 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	www "github.com/decred/politeia/politeiawww/api/www/v1"
+	"github.com/decred/politeia/util"
+)
+
+// invoiceCSVColumns contains the invoice csv column names in the order that
+// cmsutil.ParseInvoiceCSV expects them.
+var invoiceCSVColumns = []string{
+	"type",
+	"subtype",
+	"description",
+	"token",
+	"hours",
+	"cost",
+}
+
+// CSVTemplateCmd prints an example invoice csv.
+type CSVTemplateCmd struct {
+	Out   string `long:"out" optional:"true"`   // Write template to this path
+	Force bool   `long:"force" optional:"true"` // Overwrite existing --out file
+}
+
+// Execute executes the csv template command.
+func (cmd *CSVTemplateCmd) Execute(args []string) error {
+	b, err := invoiceCSVTemplate()
+	if err != nil {
+		return err
+	}
+
+	if cmd.Out == "" {
+		fmt.Printf("%s", b)
+		return nil
+	}
+
+	path := util.CleanAndExpandPath(cmd.Out)
+	if _, err := os.Stat(path); err == nil && !cmd.Force {
+		return fmt.Errorf("file %v already exists; use --force to "+
+			"overwrite it", path)
+	}
+	err = ioutil.WriteFile(path, b, 0600)
+	if err != nil {
+		return fmt.Errorf("WriteFile %v: %v", path, err)
+	}
+	return nil
+}
+
+// invoiceCSVTemplate returns an example invoice csv that uses the invoice
+// policy comment character and field delimiter.  It contains one example line
+// item for each line item type.
+func invoiceCSVTemplate() ([]byte, error) {
+	if len(invoiceCSVColumns) != www.PolicyInvoiceLineItemCount {
+		return nil, fmt.Errorf("invoice csv template has %v columns; "+
+			"policy requires %v", len(invoiceCSVColumns),
+			www.PolicyInvoiceLineItemCount)
+	}
+
+	var b bytes.Buffer
+	c := string(www.PolicyInvoiceCommentChar)
+	d := string(www.PolicyInvoiceFieldDelimiterChar)
+	fmt.Fprintf(&b, "%v Invoice line items. Lines that begin with %v are "+
+		"ignored.\n", c, c)
+	fmt.Fprintf(&b, "%v Columns: ", c)
+	for i, v := range invoiceCSVColumns {
+		if i > 0 {
+			b.WriteString(d)
+		}
+		b.WriteString(v)
+	}
+	b.WriteString("\n")
+	fmt.Fprintf(&b, "%v   type:        labor, expense, misc or credit\n", c)
+	fmt.Fprintf(&b, "%v   subtype:     category of the work or expense\n", c)
+	fmt.Fprintf(&b, "%v   description: description of the work or expense\n", c)
+	fmt.Fprintf(&b, "%v   token:       censorship token of the related "+
+		"proposal (optional)\n", c)
+	fmt.Fprintf(&b, "%v   hours:       hours worked, e.g. 7.5 or 7:30 "+
+		"(labor only)\n", c)
+	fmt.Fprintf(&b, "%v   cost:        total cost of the line item "+
+		"(negative for credits)\n", c)
+	fmt.Fprintf(&b, "%v   currency:    currency code of the cost (optional, "+
+		"not for labor)\n", c)
+	fmt.Fprintf(&b, "%v   startdate:   first day worked, YYYY-MM-DD "+
+		"(optional)\n", c)
+	fmt.Fprintf(&b, "%v   enddate:     last day worked, YYYY-MM-DD "+
+		"(optional)\n", c)
+	fmt.Fprintf(&b, "%v   tags:        labels separated by ; e.g. "+
+		"sprint-3;infra (optional)\n", c)
+
+	w := csv.NewWriter(&b)
+	w.Comma = www.PolicyInvoiceFieldDelimiterChar
+	err := w.WriteAll([][]string{
+		{"labor", "development", "Implemented the invoice csv template",
+			"", "10", "4000"},
+		{"expense", "hosting", "Server hosting", "", "", "2500"},
+		{"misc", "conference", "Conference ticket", "", "", "30000"},
+		{"credit", "advance", "Advance paid in the previous month", "", "",
+			"-5000"},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
+
+// csvTemplateHelpMsg is the output of the help command when 'csvtemplate' is
+// specified.
+const csvTemplateHelpMsg = `csvtemplate [flags]
+
+Print an example invoice csv that can be used with newinvoice. The template
+contains the expected columns in order and one example line item for each
+line item type (labor, expense, misc, credit).
+
+Arguments: None
+
+Flags:
+  --out              (string, optional)   Write the template to the specified
+                                          path instead of printing it
+  --force            (bool, optional)     Overwrite the --out file if it exists`
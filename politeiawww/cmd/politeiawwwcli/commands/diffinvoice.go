@@ -0,0 +1,138 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/decred/politeia/politeiawww/api/cms/v1"
+	"github.com/decred/politeia/util"
+)
+
+// DiffInvoiceCmd prints the line item differences between two invoices.
+type DiffInvoiceCmd struct {
+	Args struct {
+		Old string `positional-arg-name:"old" required:"true"` // Token or invoice.json path
+		New string `positional-arg-name:"new" required:"true"` // Token or invoice.json path
+	} `positional-args:"true"`
+}
+
+// Execute executes the diff invoice command.
+func (cmd *DiffInvoiceCmd) Execute(args []string) error {
+	oldInput, err := loadInvoiceInput(cmd.Args.Old)
+	if err != nil {
+		return err
+	}
+	newInput, err := loadInvoiceInput(cmd.Args.New)
+	if err != nil {
+		return err
+	}
+
+	diff := diffLineItems(oldInput.LineItems, newInput.LineItems)
+	if len(diff) == 0 {
+		fmt.Printf("Invoices are identical\n")
+		return nil
+	}
+	for _, v := range diff {
+		fmt.Printf("%v\n", v)
+	}
+
+	return nil
+}
+
+// loadInvoiceInput returns the invoice input of the specified invoice.  The
+// invoice can either be the path to a local invoice.json file or the
+// censorship token of a submitted invoice.
+func loadInvoiceInput(invoice string) (*v1.InvoiceInput, error) {
+	path := util.CleanAndExpandPath(invoice)
+	if _, err := os.Stat(path); err == nil {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("ReadFile %v: %v", path, err)
+		}
+		var invInput v1.InvoiceInput
+		err = json.Unmarshal(b, &invInput)
+		if err != nil {
+			return nil, fmt.Errorf("unmarshal %v: %v", path, err)
+		}
+		return &invInput, nil
+	}
+
+	// Get server's public key
+	iv, err := newInvoiceVerifier()
+	if err != nil {
+		return nil, err
+	}
+
+	// Get invoice
+	idr, err := client.InvoiceDetails(invoice)
+	if err != nil {
+		return nil, err
+	}
+
+	// Verify invoice censorship record
+	err = iv.verify(idr.Invoice)
+	if err != nil {
+		return nil, fmt.Errorf("unable to verify invoice %v: %v",
+			idr.Invoice.CensorshipRecord.Token, err)
+	}
+
+	return decodeInvoiceInput(idr.Invoice)
+}
+
+// diffLineItems compares the line items of two invoices by line number and
+// returns a description of each line item that was added, removed or
+// changed.
+func diffLineItems(oldItems, newItems []v1.LineItemsInput) []string {
+	n := len(oldItems)
+	if len(newItems) > n {
+		n = len(newItems)
+	}
+	var diff []string
+	for i := 0; i < n; i++ {
+		switch {
+		case i >= len(oldItems):
+			diff = append(diff, fmt.Sprintf("+ line %v: %v", i+1,
+				formatLineItem(newItems[i])))
+		case i >= len(newItems):
+			diff = append(diff, fmt.Sprintf("- line %v: %v", i+1,
+				formatLineItem(oldItems[i])))
+		case newLineItemKey(oldItems[i]) != newLineItemKey(newItems[i]):
+			diff = append(diff, fmt.Sprintf("~ line %v: %v -> %v", i+1,
+				formatLineItem(oldItems[i]), formatLineItem(newItems[i])))
+		}
+	}
+	return diff
+}
+
+// formatLineItem returns the line item formatted as a csv record.
+func formatLineItem(li v1.LineItemsInput) string {
+	s := fmt.Sprintf("%v,%v,%q,%v,%v,%v", lineItemTypeNames[li.Type],
+		li.Subtype, li.Description, li.ProposalToken, li.Hours, li.TotalCost)
+	if li.Currency != "" {
+		s += "," + li.Currency
+	}
+	return s
+}
+
+// diffInvoiceHelpMsg is the output of the help command when 'diffinvoice'
+// is specified.
+const diffInvoiceHelpMsg = `diffinvoice "old" "new"
+
+Compare the line items of two invoices by line number and print the line
+items that were added (+), removed (-) or changed (~). Each invoice can be the
+censorship token of a submitted invoice or the path to a local invoice.json
+file, e.g. one saved using 'newinvoice --out'.
+
+Arguments:
+1. old      (string, required)   Token or invoice.json path of the old invoice
+2. new      (string, required)   Token or invoice.json path of the new invoice
+
+Result:
+~ line 2: labor,development,"...",,10,400 -> labor,development,"...",,12,480
++ line 3: expense,hosting,"...",,0,20`
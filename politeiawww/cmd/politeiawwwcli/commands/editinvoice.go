@@ -5,17 +5,13 @@
 package commands
 
 import (
-	"encoding/base64"
 	"encoding/hex"
-	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"path/filepath"
+	"os"
+	"strings"
 
-	"github.com/decred/politeia/politeiad/api/v1/mime"
 	"github.com/decred/politeia/politeiawww/api/cms/v1"
-	www "github.com/decred/politeia/politeiawww/api/www/v1"
-	"github.com/decred/politeia/util"
+	"golang.org/x/crypto/ssh/terminal"
 )
 
 // EditInvoiceCmd edits an existing invoice.
@@ -27,6 +23,23 @@ type EditInvoiceCmd struct {
 		CSV         string   `positional-arg-name:"csvfile"`         // Invoice CSV file
 		Attachments []string `positional-arg-name:"attachmentfiles"` // Invoice attachments
 	} `positional-args:"true" optional:"true"`
+	Identity        string `long:"identity" optional:"true"`              // Identity file used to sign
+	Yes             bool   `long:"yes" optional:"true"`                   // Edit without confirming the diff
+	Delimiter       string `long:"delimiter" optional:"true"`             // Line item field delimiter
+	SkipHeader      bool   `long:"skip-header" optional:"true"`           // Ignore the first csv record
+	FieldMapping    string `long:"field-mapping" optional:"true"`         // Csv column of each line item field
+	LazyQuotes      bool   `long:"lazy-quotes" optional:"true"`           // Accept stray quotes in fields
+	Rate            uint   `long:"rate" optional:"true"`                  // Expected labor rate (atoms/hour)
+	Precision       *uint  `long:"precision" optional:"true"`             // Decimal places of hours and costs
+	MaxLineItems    uint   `long:"max-line-items" optional:"true"`        // Maximum number of line items
+	ValidateSubtype bool   `long:"validate-subtypes" optional:"true"`     // Validate subtypes against the server
+	AllowDuplicates bool   `long:"allow-duplicates" optional:"true"`      // Warn on duplicate line items
+	StrictHours     bool   `long:"strict-hours" optional:"true"`          // Fail on implausible labor hours
+	NoTokenCheck    bool   `long:"no-token-format-check" optional:"true"` // Skip proposal token format check
+	VerifyTokens    bool   `long:"verify-tokens" optional:"true"`         // Warn on unknown or inactive proposals
+	StrictTokens    bool   `long:"strict-tokens" optional:"true"`         // Fail on unknown or inactive proposals
+	Sort            bool   `long:"sort" optional:"true"`                  // Sort line items canonically
+	MaxSize         uint   `long:"max-size" optional:"true"`              // Maximum total invoice size (bytes)
 }
 
 // Execute executes the edit invoice command.
@@ -37,81 +50,108 @@ func (cmd *EditInvoiceCmd) Execute(args []string) error {
 	csvFile := cmd.Args.CSV
 	attachmentFiles := cmd.Args.Attachments
 
+	err := validateInvoiceDate(int(month), int(year))
+	if err != nil {
+		return err
+	}
+
 	if csvFile == "" {
 		return errInvoiceCSVNotFound
 	}
 
-	// Check for user identity
-	if cfg.Identity == nil {
-		return errUserIdentityNotFound
+	// Load user identity
+	id, err := loadInvoiceIdentity(cmd.Identity)
+	if err != nil {
+		return err
 	}
 
 	// Get server public key
-	vr, err := client.Version()
+	iv, err := newInvoiceVerifier()
 	if err != nil {
 		return err
 	}
 
-	var csv []byte
-	files := make([]www.File, 0, www.PolicyMaxImages+1)
-	// Read markdown file into memory and convert to type File
-	fpath := util.CleanAndExpandPath(csvFile)
-	csv, err = ioutil.ReadFile(fpath)
+	// Parse and check the line items the same way that newinvoice
+	// does.  Multiple comma separated csv files are merged into a
+	// single invoice.
+	opts, err := invoiceParseFlags{
+		delimiter:       cmd.Delimiter,
+		skipHeader:      cmd.SkipHeader,
+		fieldMapping:    cmd.FieldMapping,
+		lazyQuotes:      cmd.LazyQuotes,
+		noTokenCheck:    cmd.NoTokenCheck,
+		rate:            cmd.Rate,
+		precision:       cmd.Precision,
+		maxLineItems:    cmd.MaxLineItems,
+		validateSubtype: cmd.ValidateSubtype,
+	}.options(int(month), int(year))
 	if err != nil {
-		return fmt.Errorf("ReadFile %v: %v", fpath, err)
+		return err
 	}
-
-	invInput, err := validateParseCSV(csv)
+	invInput, sources, err := parseInvoiceCSVFiles(strings.Split(csvFile, ","),
+		opts)
+	if err != nil {
+		return err
+	}
+	err = checkLineItems(invInput.LineItems, sources, int(month), int(year),
+		invoiceCheckFlags{
+			allowDuplicates: cmd.AllowDuplicates,
+			strictHours:     cmd.StrictHours,
+			verifyTokens:    cmd.VerifyTokens,
+			strictTokens:    cmd.StrictTokens,
+		})
 	if err != nil {
-		return fmt.Errorf("Parsing CSV failed: %v", err)
+		return err
+	}
+
+	// Canonicalize the line item order if specified
+	if cmd.Sort {
+		sortLineItems(invInput.LineItems)
 	}
 
 	invInput.Month = uint16(month)
 	invInput.Year = uint16(year)
 
-	b, err := json.Marshal(invInput)
+	// Show the changes against the current version of the invoice and
+	// confirm them before the invoice is overwritten.
+	idr, err := client.InvoiceDetails(token)
 	if err != nil {
-		return fmt.Errorf("Marshal: %v", err)
+		return err
 	}
-
-	f := www.File{
-		Name:    "invoice.json",
-		MIME:    mime.DetectMimeType(b),
-		Digest:  hex.EncodeToString(util.Digest(b)),
-		Payload: base64.StdEncoding.EncodeToString(b),
+	err = iv.verify(idr.Invoice)
+	if err != nil {
+		return fmt.Errorf("unable to verify invoice %v: %v", token, err)
+	}
+	oldInput, err := decodeInvoiceInput(idr.Invoice)
+	if err != nil {
+		return fmt.Errorf("invoice %v: %v", token, err)
+	}
+	err = confirmInvoiceEdit(oldInput, invInput, cmd.Yes)
+	if err != nil {
+		return err
 	}
 
-	files = append(files, f)
-
-	// Read attachment files into memory and convert to type File
-	for _, file := range attachmentFiles {
-		path := util.CleanAndExpandPath(file)
-		attachment, err := ioutil.ReadFile(path)
-		if err != nil {
-			return fmt.Errorf("ReadFile %v: %v", path, err)
-		}
-
-		f := www.File{
-			Name:    filepath.Base(file),
-			MIME:    mime.DetectMimeType(attachment),
-			Digest:  hex.EncodeToString(util.Digest(attachment)),
-			Payload: base64.StdEncoding.EncodeToString(attachment),
-		}
-
-		files = append(files, f)
+	maxSize := invoiceMaxSize
+	if cmd.MaxSize != 0 {
+		maxSize = int(cmd.MaxSize)
+	}
+	files, err := buildInvoiceFiles(invInput, attachmentFiles, false,
+		maxSize)
+	if err != nil {
+		return err
 	}
 
 	// Compute merkle root and sign it
-	sig, err := signedMerkleRoot(files, cfg.Identity)
+	sig, err := signFiles(files, id)
 	if err != nil {
-		return fmt.Errorf("SignMerkleRoot: %v", err)
+		return err
 	}
 
 	// Setup edit invoice request
 	ei := &v1.EditInvoice{
 		Token:     token,
 		Files:     files,
-		PublicKey: hex.EncodeToString(cfg.Identity.Public.Key[:]),
+		PublicKey: hex.EncodeToString(id.Public.Key[:]),
 		Signature: sig,
 	}
 
@@ -122,6 +162,7 @@ func (cmd *EditInvoiceCmd) Execute(args []string) error {
 	}
 
 	// Send request
+	printProgress("Submitting invoice...\n")
 	eir, err := client.EditInvoice(ei)
 	if err != nil {
 		return err
@@ -134,7 +175,7 @@ func (cmd *EditInvoiceCmd) Execute(args []string) error {
 		Signature:        ei.Signature,
 		CensorshipRecord: eir.Invoice.CensorshipRecord,
 	}
-	err = verifyInvoice(pr, vr.PubKey)
+	err = iv.verify(pr)
 	if err != nil {
 		return fmt.Errorf("unable to verify invoice %v: %v",
 			eir.Invoice.CensorshipRecord.Token, err)
@@ -144,18 +185,82 @@ func (cmd *EditInvoiceCmd) Execute(args []string) error {
 	return printJSON(eir)
 }
 
+// confirmInvoiceEdit prints the line item diff between the current and the
+// edited version of an invoice and asks the user to confirm the edit.  No
+// confirmation is asked for when yes is set, in which case the diff is only
+// printed as progress output.  An error is returned if the edit needs to be
+// confirmed but stdin is not a terminal.
+func confirmInvoiceEdit(oldInput, newInput *v1.InvoiceInput, yes bool) error {
+	printf := printProgress
+	if !yes {
+		printf = func(format string, args ...interface{}) {
+			fmt.Printf(format, args...)
+		}
+	}
+
+	if oldInput.Month != newInput.Month || oldInput.Year != newInput.Year {
+		printf("~ month: %02d/%v -> %02d/%v\n", oldInput.Month,
+			oldInput.Year, newInput.Month, newInput.Year)
+	}
+	diff := diffLineItems(oldInput.LineItems, newInput.LineItems)
+	if len(diff) == 0 {
+		printf("Line items are unchanged\n")
+	}
+	for _, v := range diff {
+		printf("%v\n", v)
+	}
+
+	if yes {
+		return nil
+	}
+	if !terminal.IsTerminal(int(os.Stdin.Fd())) {
+		return fmt.Errorf("the edit must be confirmed; use --yes to edit " +
+			"the invoice without confirming")
+	}
+	ok, err := promptConfirm("Overwrite the invoice with these changes?")
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("invoice not edited")
+	}
+	return nil
+}
+
 // editInvoiceHelpMsg is the output of the help command when 'editinvoice'
 // is specified.
-const editInvoiceHelpMsg = `editinvoice [flags] "month" "year" token" "csvfile" "attachmentfiles" 
+const editInvoiceHelpMsg = `editinvoice [flags] "month" "year" "token" "csvfile" "attachmentfiles" 
 
-Edit a invoice.
+Edit a invoice. The edited csv is parsed and its line items are checked the
+same way that newinvoice does. The line items of the current version of the
+invoice are compared against the edited csv and the changes are printed as
+added (+), removed (-) or changed (~) line items. The edit must be confirmed
+before the invoice is overwritten unless --yes is specified. Confirmation
+requires a terminal, so use --yes when reading the csv from stdin.
 
 Arguments:
 1. month             (uint, required)     Invoice Month
 2. year              (uint, required)     Invoice Year
-1. token             (string, required)   Invoice censorship token
-2. csvfile           (string, required)   Edited invoice 
-3. attachmentfiles   (string, optional)   Attachments 
+3. token             (string, required)   Invoice censorship token
+4. csvfile           (string, required)   Edited invoice (- to read from
+                                          stdin). Multiple comma separated files
+                                          are combined.
+5. attachmentfiles   (string, optional)   Attachments 
+
+Flags:
+  --identity         (string, optional)   Path of the identity file used to
+                                          sign the invoice. Defaults to the
+                                          POLITEIAWWWCLI_IDENTITY environment
+                                          variable or the logged in user's
+                                          identity.
+  --yes              (bool, optional)     Edit the invoice without confirming
+                                          the changes
+
+The --delimiter, --skip-header, --field-mapping, --lazy-quotes, --rate,
+--precision, --max-line-items, --validate-subtypes, --allow-duplicates,
+--strict-hours, --no-token-format-check, --verify-tokens, --strict-tokens,
+--sort and --max-size flags are applied to the edited invoice. See
+'newinvoice' for their descriptions.
 
 Request:
 {
@@ -6,9 +6,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"io/ioutil"
-	"path/filepath"
 
-	"github.com/decred/politeia/politeiad/api/v1/mime"
 	"github.com/decred/politeia/politeiawww/api/www/v1"
 	"github.com/decred/politeia/util"
 )
@@ -71,37 +69,19 @@ func (cmd *EditProposalCmd) Execute(args []string) error {
 		}
 	}
 
-	f := v1.File{
-		Name:    "index.md",
-		MIME:    mime.DetectMimeType(md),
-		Digest:  hex.EncodeToString(util.Digest(md)),
-		Payload: base64.StdEncoding.EncodeToString(md),
-	}
-
-	files = append(files, f)
+	files = append(files, newFile("index.md", md))
 
 	// Read attachment files into memory and convert to type File
-	for _, file := range attachmentFiles {
-		path := util.CleanAndExpandPath(file)
-		attachment, err := ioutil.ReadFile(path)
-		if err != nil {
-			return fmt.Errorf("ReadFile %v: %v", path, err)
-		}
-
-		f := v1.File{
-			Name:    filepath.Base(file),
-			MIME:    mime.DetectMimeType(attachment),
-			Digest:  hex.EncodeToString(util.Digest(attachment)),
-			Payload: base64.StdEncoding.EncodeToString(attachment),
-		}
-
-		files = append(files, f)
+	attachments, err := readAttachments(attachmentFiles)
+	if err != nil {
+		return err
 	}
+	files = append(files, attachments...)
 
 	// Compute merkle root and sign it
-	sig, err := signedMerkleRoot(files, cfg.Identity)
+	sig, err := signFiles(files, cfg.Identity)
 	if err != nil {
-		return fmt.Errorf("SignMerkleRoot: %v", err)
+		return err
 	}
 
 	// Setup edit proposal request
@@ -0,0 +1,102 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/decred/politeia/politeiawww/api/cms/v1"
+)
+
+// EstimateInvoiceCmd prints a payout estimate for an invoice csv using the
+// provided DCR/USD rate.
+type EstimateInvoiceCmd struct {
+	Args struct {
+		CSV string `positional-arg-name:"csvfile" required:"true"` // Invoice CSV file
+	} `positional-args:"true"`
+	USDRate    float64 `long:"usdrate" optional:"true"`     // USD price of 1 DCR
+	Delimiter  string  `long:"delimiter" optional:"true"`   // Line item field delimiter
+	SkipHeader bool    `long:"skip-header" optional:"true"` // Ignore the first csv record
+}
+
+// Execute executes the estimate invoice command.
+func (cmd *EstimateInvoiceCmd) Execute(args []string) error {
+	if cmd.USDRate <= 0 {
+		return fmt.Errorf("a positive --usdrate is required")
+	}
+
+	opts, err := invoiceParseFlags{
+		delimiter:  cmd.Delimiter,
+		skipHeader: cmd.SkipHeader,
+	}.options(0, 0)
+	if err != nil {
+		return err
+	}
+
+	invInput, sources, err := parseInvoiceCSVFiles(strings.Split(cmd.Args.CSV, ","),
+		opts)
+	if err != nil {
+		return err
+	}
+
+	// Line items with a cost in another currency cannot be converted
+	// using the DCR/USD rate so they are left out of the estimate.
+	lineItems := make([]v1.LineItemsInput, 0, len(invInput.LineItems))
+	for _, li := range invInput.LineItems {
+		if li.Currency != "" && li.Currency != "USD" {
+			fmt.Fprintf(os.Stderr, "Warning: the line item on %v has a "+
+				"cost in %v and is not included in the estimate\n",
+				lineSource(sources, li.LineNumber), li.Currency)
+			continue
+		}
+		lineItems = append(lineItems, li)
+	}
+
+	t := invoiceTotals(lineItems)
+	total := t.total()
+
+	fmt.Printf("Labor hours       : %v\n", t.laborHours)
+	fmt.Printf("Labor cost        : %.2f USD\n", t.laborCost)
+	fmt.Printf("Expense/misc cost : %.2f USD\n", t.expenseCost)
+	fmt.Printf("Credits           : %.2f USD\n", t.credits)
+	fmt.Printf("Total             : %.2f USD\n", total)
+	fmt.Printf("DCR/USD rate      : %v\n", cmd.USDRate)
+	fmt.Printf("Estimated payout  : %.8f DCR\n", total/cmd.USDRate)
+
+	return nil
+}
+
+// estimateInvoiceHelpMsg is the output of the help command when
+// 'estimateinvoice' is specified.
+const estimateInvoiceHelpMsg = `estimateinvoice [flags] "csvfile"
+
+Print a payout estimate for an invoice csv. The line item costs are assumed
+to be in USD and are converted to DCR using the provided DCR/USD rate. Line
+items with a cost in another currency are left out of the estimate. Credit
+line items are subtracted from the total. The rate is not fetched from an
+exchange.
+
+Arguments:
+1. csvFile           (string, required)   Invoice CSV file (- to read from
+                                          stdin). Multiple comma separated files
+                                          are combined.
+
+Flags:
+  --usdrate          (float, required)    USD price of 1 DCR
+  --delimiter        (string, optional)   Line item field delimiter. Defaults
+                                          to the policy delimiter (,). Use \t
+                                          for tab separated files.
+  --skip-header      (bool, optional)     Ignore the first line of the csv
+
+Result:
+Labor hours       : 10
+Labor cost        : 400.00 USD
+Expense/misc cost : 25.00 USD
+Credits           : 0.00 USD
+Total             : 425.00 USD
+DCR/USD rate      : 20
+Estimated payout  : 21.25000000 DCR`
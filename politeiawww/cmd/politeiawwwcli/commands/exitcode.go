@@ -0,0 +1,87 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"encoding/csv"
+	"net/url"
+
+	wwwclient "github.com/decred/politeia/politeiawww/cmd/politeiawwwcli/client"
+	"github.com/decred/politeia/politeiawww/cmsutil"
+)
+
+// Exit codes that politeiawwwcli exits with for each class of error so that
+// scripts can react to a failure without parsing the error message.
+const (
+	ExitCodeError      = 1 // Unclassified error
+	ExitCodeValidation = 2 // Local validation error, e.g. a malformed csv
+	ExitCodeIdentity   = 3 // Identity or signing error
+	ExitCodeNetwork    = 4 // Network error or transient server error
+	ExitCodeRejected   = 5 // Request rejected by the server
+)
+
+// exitCodeError is a command error that carries the exit code of its error
+// class.
+type exitCodeError struct {
+	code int
+	err  error
+}
+
+// Error satisfies the error interface.
+func (e *exitCodeError) Error() string {
+	return e.err.Error()
+}
+
+// validationError marks the passed in error as a local validation error.
+func validationError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &exitCodeError{
+		code: ExitCodeValidation,
+		err:  err,
+	}
+}
+
+// identityError marks the passed in error as an identity or signing error.
+func identityError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &exitCodeError{
+		code: ExitCodeIdentity,
+		err:  err,
+	}
+}
+
+// ExitCode returns the exit code for the passed in command error.  Errors
+// that do not belong to a known error class return ExitCodeError.
+func ExitCode(err error) int {
+	switch e := err.(type) {
+	case nil:
+		return 0
+	case *exitCodeError:
+		return e.code
+	case *wwwclient.ResponseError:
+		if isTransientError(e) {
+			return ExitCodeNetwork
+		}
+		return ExitCodeRejected
+	case *url.Error:
+		return ExitCodeNetwork
+	case *cmsutil.LineItemError, *cmsutil.LineItemCountError,
+		*csv.ParseError:
+		return ExitCodeValidation
+	}
+
+	switch err {
+	case errUserIdentityNotFound:
+		return ExitCodeIdentity
+	case errInvoiceCSVNotFound, cmsutil.ErrNoLineItems:
+		return ExitCodeValidation
+	}
+
+	return ExitCodeError
+}
@@ -0,0 +1,173 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/decred/politeia/politeiawww/api/cms/v1"
+	www "github.com/decred/politeia/politeiawww/api/www/v1"
+	"github.com/decred/politeia/politeiawww/cmsutil"
+	"github.com/decred/politeia/util"
+)
+
+// ExportInvoiceCmd writes the line items of an invoice to a csv file that can
+// be parsed by newinvoice.
+type ExportInvoiceCmd struct {
+	Args struct {
+		Invoice string `positional-arg-name:"invoice" required:"true"` // Token or invoice.json path
+	} `positional-args:"true"`
+	Out          string `long:"out" optional:"true"`           // Write csv to this path
+	Force        bool   `long:"force" optional:"true"`         // Overwrite existing --out file
+	CommentsFrom string `long:"comments-from" optional:"true"` // Copy comments from this csv
+}
+
+// Execute executes the export invoice command.
+func (cmd *ExportInvoiceCmd) Execute(args []string) error {
+	invInput, err := loadInvoiceInput(cmd.Args.Invoice)
+	if err != nil {
+		return err
+	}
+
+	// Get the comments of the original csv if specified
+	var comments []csvComment
+	if cmd.CommentsFrom != "" {
+		csv, err := readInvoiceCSV(cmd.CommentsFrom)
+		if err != nil {
+			return err
+		}
+		comments = parseCSVComments(csv, cmsutil.Options{})
+	}
+
+	b, err := invoiceCSV(invInput, comments)
+	if err != nil {
+		return err
+	}
+
+	if cmd.Out == "" {
+		fmt.Printf("%s", b)
+		return nil
+	}
+
+	path := util.CleanAndExpandPath(cmd.Out)
+	if _, err := os.Stat(path); err == nil && !cmd.Force {
+		return fmt.Errorf("file %v already exists; use --force to "+
+			"overwrite it", path)
+	}
+	err = ioutil.WriteFile(path, b, 0600)
+	if err != nil {
+		return fmt.Errorf("WriteFile %v: %v", path, err)
+	}
+	return nil
+}
+
+// invoiceCSV converts the line items of the passed in invoice input into csv
+// records in the column order that cmsutil.ParseInvoiceCSV expects, delimited
+// by the delimiter that the invoice was parsed with or the policy delimiter if the
+// invoice does not record it.  The optional trailing fields are only written when they
+// are set.  The passed in comments are written before the line item that they
+// preceded in the original csv.
+func invoiceCSV(invInput *v1.InvoiceInput, comments []csvComment) ([]byte, error) {
+	var b bytes.Buffer
+	w := csv.NewWriter(&b)
+	w.Comma = www.PolicyInvoiceFieldDelimiterChar
+	if md := invInput.CSVMetadata; md != nil && md.FieldDelimiterChar != 0 {
+		w.Comma = md.FieldDelimiterChar
+	}
+
+	writeComments := func(before int) {
+		w.Flush()
+		for len(comments) > 0 && comments[0].Before <= before {
+			b.WriteString(comments[0].Text + "\n")
+			comments = comments[1:]
+		}
+	}
+
+	for i, li := range invInput.LineItems {
+		writeComments(i)
+
+		var hours string
+		if li.Type == v1.LineItemTypeLabor || li.Hours != 0 {
+			hours = strconv.FormatFloat(li.Hours, 'f', -1, 64)
+		}
+		r := []string{
+			lineItemTypeNames[li.Type],
+			li.Subtype,
+			li.Description,
+			li.ProposalToken,
+			hours,
+			strconv.FormatFloat(li.TotalCost, 'f', -1, 64),
+			li.Currency,
+			formatLineItemDate(li.StartDate),
+			formatLineItemDate(li.EndDate),
+			strings.Join(li.Tags, ";"),
+		}
+
+		// Drop the unused optional fields
+		n := len(r)
+		for n > www.PolicyInvoiceLineItemCount && r[n-1] == "" {
+			n--
+		}
+		err := w.Write(r[:n])
+		if err != nil {
+			return nil, err
+		}
+	}
+	writeComments(len(invInput.LineItems))
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// formatLineItemDate formats a line item date unix timestamp.  Dates at
+// midnight UTC are formatted as YYYY-MM-DD, all other dates as RFC3339.  An
+// empty string is returned for a zero timestamp.
+func formatLineItemDate(date int64) string {
+	if date == 0 {
+		return ""
+	}
+	t := time.Unix(date, 0).UTC()
+	if t.Equal(t.Truncate(24 * time.Hour)) {
+		return t.Format("2006-01-02")
+	}
+	return t.Format(time.RFC3339)
+}
+
+// exportInvoiceHelpMsg is the output of the help command when 'exportinvoice'
+// is specified.
+const exportInvoiceHelpMsg = `exportinvoice [flags] "invoice"
+
+Export the line items of an invoice to csv. The csv uses the column order that
+newinvoice expects, so an exported invoice can be edited and submitted again.
+The delimiter that the invoice csv was parsed with is used if the invoice
+records it, otherwise the policy delimiter (,) is used. Comments of the
+original csv are not part of the invoice. Use --comments-from to copy the
+comment and blank lines of the original csv into the exported csv.
+
+Arguments:
+1. invoice     (string, required)   Censorship token of a submitted invoice or
+                                    the path to a local invoice.json file
+
+Flags:
+  --out              (string, optional)   Write the csv to the specified path
+                                          instead of printing it
+  --force            (bool, optional)     Overwrite the --out file if it exists
+  --comments-from    (string, optional)   Original csv whose comment and blank
+                                          lines are reattached to the exported
+                                          line items
+
+Result:
+labor,development,Implemented the invoice csv template,,10,4000
+expense,hosting,Server hosting,,,2500`
@@ -0,0 +1,71 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/decred/politeia/politeiawww/cmsutil"
+)
+
+func TestInvoiceCSVRoundTrip(t *testing.T) {
+	token := "e1d5c5a3a27d6e4b2c3d1f9e8b7a6c5d4e3f2a1b0c9d8e7f6a5b4c3d2e1f0a9b"
+	data := []byte(`# Invoice line items
+labor,development,"Implemented the ""export"" command",` + token + `,10.5,420
+labor, design , Logo design ,,2,80,,2019-01-02,2019-01-03
+expense,hosting,"Server hosting, January",,,25.75,USD
+misc,conference,Ticket,,,300,eur,2019-01-10T09:00:00Z
+labor,development,Tagged,,1,40,,,,"sprint-1, infra"
+`)
+	opts := cmsutil.Options{Month: 1, Year: 2019}
+
+	want, err := cmsutil.ParseInvoiceCSV(data, opts)
+	if err != nil {
+		t.Fatalf("ParseInvoiceCSV: %v", err)
+	}
+
+	b, err := invoiceCSV(want, nil)
+	if err != nil {
+		t.Fatalf("invoiceCSV: %v", err)
+	}
+
+	got, err := cmsutil.ParseInvoiceCSV(b, opts)
+	if err != nil {
+		t.Fatalf("ParseInvoiceCSV exported csv: %v\n%s", err, b)
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("round trip mismatch:\ngot  %+v\nwant %+v\ncsv:\n%s",
+			got.LineItems, want.LineItems, b)
+	}
+}
+
+func TestInvoiceCSVComments(t *testing.T) {
+	data := []byte(`# January invoice
+labor,development,"Multi
+# not a comment",,10,400
+
+# Expenses
+expense,hosting,Server hosting,,,25
+# End
+`)
+	invInput, comments, err := validateParseCSVWithComments(data,
+		cmsutil.Options{})
+	if err != nil {
+		t.Fatalf("ParseInvoiceCSVWithComments: %v", err)
+	}
+	if len(comments) != 4 {
+		t.Fatalf("got %v comments, want 4: %+v", len(comments), comments)
+	}
+
+	b, err := invoiceCSV(invInput, comments)
+	if err != nil {
+		t.Fatalf("invoiceCSV: %v", err)
+	}
+	if string(b) != string(data) {
+		t.Fatalf("got csv:\n%s\nwant:\n%s", b, data)
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/decred/politeia/util"
+)
+
+// GetInvoiceAttachmentsCmd downloads the attachments of an invoice.
+type GetInvoiceAttachmentsCmd struct {
+	Args struct {
+		Token string `positional-arg-name:"token" required:"true"` // Invoice censorship token
+	} `positional-args:"true"`
+	Out   string `long:"out" optional:"true"`   // Output directory
+	Force bool   `long:"force" optional:"true"` // Overwrite existing files
+}
+
+// Execute executes the get invoice attachments command.
+func (cmd *GetInvoiceAttachmentsCmd) Execute(args []string) error {
+	dir := "."
+	if cmd.Out != "" {
+		dir = util.CleanAndExpandPath(cmd.Out)
+	}
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return err
+	}
+
+	// Get server public key
+	iv, err := newInvoiceVerifier()
+	if err != nil {
+		return err
+	}
+
+	// Get invoice
+	idr, err := client.InvoiceDetails(cmd.Args.Token)
+	if err != nil {
+		return err
+	}
+
+	// Verify invoice censorship record
+	err = iv.verify(idr.Invoice)
+	if err != nil {
+		return fmt.Errorf("unable to verify invoice %v: %v",
+			idr.Invoice.CensorshipRecord.Token, err)
+	}
+
+	names := make(map[string]bool, len(idr.Invoice.Files))
+	var written int
+	for _, f := range idr.Invoice.Files {
+		if f.Name == "invoice.json" {
+			continue
+		}
+
+		err := verifyFileDigest(f)
+		if err != nil {
+			return fmt.Errorf("%v: %v", f.Name, err)
+		}
+		payload, err := base64.StdEncoding.DecodeString(f.Payload)
+		if err != nil {
+			return fmt.Errorf("%v: %v", f.Name, err)
+		}
+
+		// Only use the last path element so that files are never
+		// written outside of the output directory.
+		name := filepath.Base(f.Name)
+		if names[name] {
+			unique := uniqueFilename(name, names)
+			fmt.Fprintf(os.Stderr, "Warning: invoice contains more than "+
+				"one file named %v; writing %v instead\n", name, unique)
+			name = unique
+		}
+		names[name] = true
+
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil && !cmd.Force {
+			fmt.Fprintf(os.Stderr, "Warning: %v already exists; use "+
+				"--force to overwrite it\n", path)
+			continue
+		}
+		err = ioutil.WriteFile(path, payload, 0600)
+		if err != nil {
+			return fmt.Errorf("WriteFile %v: %v", path, err)
+		}
+		printProgress("%v (%vKB)\n", path, len(payload)/1024)
+		written++
+	}
+
+	printProgress("Wrote %v attachment(s) to %v\n", written, dir)
+	return nil
+}
+
+// uniqueFilename returns the passed in filename with a numeric suffix that
+// is not in the passed in set of names.
+func uniqueFilename(name string, names map[string]bool) string {
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	for i := 1; ; i++ {
+		n := fmt.Sprintf("%v-%v%v", base, i, ext)
+		if !names[n] {
+			return n
+		}
+	}
+}
+
+// getInvoiceAttachmentsHelpMsg is the output of the help command when
+// 'getinvoiceattachments' is specified.
+const getInvoiceAttachmentsHelpMsg = `getinvoiceattachments [flags] "token"
+
+Download the attachments of an invoice. The invoice censorship record is
+verified and each attachment is verified against its digest before it is
+written to the output directory using its filename. The invoice.json file is
+skipped. Attachments with the same filename are written with a numeric suffix.
+
+Arguments:
+1. token             (string, required)   Invoice censorship token
+
+Flags:
+  --out              (string, optional)   Output directory. Defaults to the
+                                          current directory.
+  --force            (bool, optional)     Overwrite existing files. Existing
+                                          files are skipped otherwise.
+
+Result:
+receipts/receipt.png (120KB)
+Wrote 1 attachment(s) to receipts`
@@ -116,10 +116,44 @@ func (cmd *HelpCmd) Execute(args []string) error {
 		fmt.Printf("%s\n", newInvoiceHelpMsg)
 	case "invoicedetails":
 		fmt.Printf("%s\n", invoiceDetailsHelpMsg)
+	case "diffinvoice":
+		fmt.Printf("%s\n", diffInvoiceHelpMsg)
 	case "editinvoice":
 		fmt.Printf("%s\n", editInvoiceHelpMsg)
 	case "setinvoicestatus":
 		fmt.Printf("%s\n", setInvoiceStatusHelpMsg)
+	case "cancelinvoice":
+		fmt.Printf("%s\n", cancelInvoiceHelpMsg)
+	case "batchinvoice":
+		fmt.Printf("%s\n", batchInvoiceHelpMsg)
+	case "cmsversion":
+		fmt.Printf("%s\n", cmsVersionHelpMsg)
+	case "csvtemplate":
+		fmt.Printf("%s\n", csvTemplateHelpMsg)
+	case "estimateinvoice":
+		fmt.Printf("%s\n", estimateInvoiceHelpMsg)
+	case "exportinvoice":
+		fmt.Printf("%s\n", exportInvoiceHelpMsg)
+	case "getinvoiceattachments":
+		fmt.Printf("%s\n", getInvoiceAttachmentsHelpMsg)
+	case "invoicereport":
+		fmt.Printf("%s\n", invoiceReportHelpMsg)
+	case "invoicepayment":
+		fmt.Printf("%s\n", invoicePaymentHelpMsg)
+	case "invoicetimeline":
+		fmt.Printf("%s\n", invoiceTimelineHelpMsg)
+	case "invoicepolicy":
+		fmt.Printf("%s\n", invoicePolicyHelpMsg)
+	case "listcontractors":
+		fmt.Printf("%s\n", listContractorsHelpMsg)
+	case "listinvoices":
+		fmt.Printf("%s\n", listInvoicesHelpMsg)
+	case "verifyfiles":
+		fmt.Printf("%s\n", verifyFilesHelpMsg)
+	case "verifyinvoice":
+		fmt.Printf("%s\n", verifyInvoiceHelpMsg)
+	case "verifyinvoices":
+		fmt.Printf("%s\n", verifyInvoicesHelpMsg)
 	default:
 		fmt.Printf("invalid command: use 'politeiawwwcli -h' " +
 			"to view a list of valid commands\n")
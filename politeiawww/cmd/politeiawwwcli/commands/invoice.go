@@ -0,0 +1,899 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"unicode/utf8"
+
+	"github.com/decred/politeia/politeiad/api/v1/mime"
+	"github.com/decred/politeia/politeiawww/api/cms/v1"
+	www "github.com/decred/politeia/politeiawww/api/www/v1"
+	wwwclient "github.com/decred/politeia/politeiawww/cmd/politeiawwwcli/client"
+	"github.com/decred/politeia/politeiawww/cmsutil"
+	"github.com/decred/politeia/util"
+)
+
+// invoiceFiles converts the passed in invoice input and attachment files into
+// the files that make up an invoice.  The invoice.json file is always the
+// first file in the returned slice.
+func invoiceFiles(invInput *v1.InvoiceInput, attachmentFiles []string) ([]www.File, error) {
+	files := make([]www.File, 0, www.PolicyMaxImages+1)
+
+	b, err := json.Marshal(invInput)
+	if err != nil {
+		return nil, fmt.Errorf("Marshal: %v", err)
+	}
+
+	files = append(files, newFile("invoice.json", b))
+
+	// Read attachment files into memory and convert to type File.
+	// The attachments are read concurrently, but are added to the
+	// files slice in the order they were specified so that the
+	// merkle root is deterministic.
+	attachments, err := readAttachments(attachmentFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	var numImages, numPDFs int
+	for i, f := range attachments {
+		// Validate the attachment type before it gets sent to
+		// the server.
+		if !mime.MimeValid(f.MIME) {
+			return nil, fmt.Errorf("attachment %v has unsupported MIME "+
+				"type %v: accepted types are %v", attachmentFiles[i],
+				f.MIME, strings.Join(mime.ValidMimeTypes(), ", "))
+		}
+		switch {
+		case f.MIME == "application/pdf":
+			numPDFs++
+			if numPDFs > www.PolicyMaxPDFs {
+				return nil, fmt.Errorf("too many PDF attachments: the "+
+					"maximum is %v", www.PolicyMaxPDFs)
+			}
+			b, err := base64.StdEncoding.DecodeString(f.Payload)
+			if err != nil {
+				return nil, fmt.Errorf("decode %v: %v", f.Name, err)
+			}
+			if len(b) > www.PolicyMaxPDFSize {
+				return nil, fmt.Errorf("PDF attachment %v is larger than "+
+					"the maximum of %v bytes", attachmentFiles[i],
+					www.PolicyMaxPDFSize)
+			}
+		case strings.HasPrefix(f.MIME, "image/"):
+			numImages++
+			if numImages > www.PolicyMaxImages {
+				return nil, fmt.Errorf("too many image attachments: "+
+					"the maximum is %v", www.PolicyMaxImages)
+			}
+		}
+
+		files = append(files, f)
+	}
+
+	return files, nil
+}
+
+// checkFilenames verifies that the names of the passed in invoice files are
+// unique.  Attachments are named after the last element of their path, so
+// attachments from different directories can have the same name.  Duplicate
+// names are an error unless rename is set, in which case the duplicates are
+// given a numeric suffix.
+func checkFilenames(files []www.File, rename bool) error {
+	names := make(map[string]bool, len(files))
+	var duplicates []string
+	for i, f := range files {
+		if !names[f.Name] {
+			names[f.Name] = true
+			continue
+		}
+		if !rename {
+			duplicates = append(duplicates, f.Name)
+			continue
+		}
+		unique := uniqueFilename(f.Name, names)
+		fmt.Fprintf(os.Stderr, "Warning: more than one attachment is "+
+			"named %v; attaching it as %v\n", f.Name, unique)
+		files[i].Name = unique
+		names[unique] = true
+	}
+	if len(duplicates) > 0 {
+		return validationError(fmt.Errorf("attachment filenames must be "+
+			"unique: %v; rename the files or use --rename-duplicates",
+			strings.Join(duplicates, ", ")))
+	}
+	return nil
+}
+
+// attachmentDirFiles returns the paths of the files in the passed in
+// directory, sorted by filename.  Subdirectories are not read.  Files with a
+// MIME type that is not accepted as an attachment are skipped with a warning
+// and files that are already in the passed in attachment files are skipped so
+// that they are not attached twice.
+func attachmentDirFiles(dir string, attachmentFiles []string) ([]string, error) {
+	dir = util.CleanAndExpandPath(dir)
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("ReadDir %v: %v", dir, err)
+	}
+
+	listed := make(map[string]bool, len(attachmentFiles))
+	for _, v := range attachmentFiles {
+		path, err := filepath.Abs(util.CleanAndExpandPath(v))
+		if err != nil {
+			return nil, err
+		}
+		listed[path] = true
+	}
+
+	// ReadDir returns the entries sorted by filename
+	files := make([]string, 0, len(fis))
+	for _, fi := range fis {
+		if !fi.Mode().IsRegular() {
+			continue
+		}
+		path, err := filepath.Abs(filepath.Join(dir, fi.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if listed[path] {
+			continue
+		}
+
+		// Only the first 512 bytes are used to detect the MIME type
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		b := make([]byte, 512)
+		n, err := io.ReadFull(f, b)
+		f.Close()
+		if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+			return nil, fmt.Errorf("read %v: %v", path, err)
+		}
+		if m := mime.DetectMimeType(b[:n]); !mime.MimeValid(m) {
+			fmt.Fprintf(os.Stderr, "Warning: skipping %v: unsupported "+
+				"MIME type %v\n", path, m)
+			continue
+		}
+
+		files = append(files, path)
+	}
+
+	return files, nil
+}
+
+// cachedPolicy caches the server policy so that it is only fetched once, even
+// when the invoices of a batch are submitted.
+var cachedPolicy *www.PolicyReply
+
+// invoicePolicy returns the server policy.
+func invoicePolicy() (*www.PolicyReply, error) {
+	if cachedPolicy != nil {
+		return cachedPolicy, nil
+	}
+	pr, err := client.Policy()
+	if err != nil {
+		return nil, err
+	}
+	cachedPolicy = pr
+	return pr, nil
+}
+
+// allowedSubtypes returns the allowed line item subtypes per line item type
+// from the server policy.  An error is returned if the server does not
+// restrict the line item subtypes.
+func allowedSubtypes() (map[v1.LineItemTypeT][]string, error) {
+	pr, err := invoicePolicy()
+	if err != nil {
+		return nil, err
+	}
+	if len(pr.InvoiceLineItemSubtypes) == 0 {
+		return nil, fmt.Errorf("server does not provide a list of " +
+			"allowed line item subtypes")
+	}
+
+	subtypes := make(map[v1.LineItemTypeT][]string,
+		len(pr.InvoiceLineItemSubtypes))
+	for t, name := range lineItemTypeNames {
+		if s, ok := pr.InvoiceLineItemSubtypes[name]; ok {
+			subtypes[t] = s
+		}
+	}
+
+	return subtypes, nil
+}
+
+// invoiceMaxSize is the maximum total size (in bytes) of the invoice.json
+// file plus attachments: the size of the maximum number of markdown files
+// plus the size of the maximum number of images and PDF files.
+const invoiceMaxSize = www.PolicyMaxMDs*www.PolicyMaxMDSize +
+	www.PolicyMaxImages*www.PolicyMaxImageSize +
+	www.PolicyMaxPDFs*www.PolicyMaxPDFSize
+
+// checkInvoiceSize returns an error that lists the size of each file if the
+// total decoded size of the passed in files exceeds maxSize bytes.
+func checkInvoiceSize(files []www.File, maxSize int) error {
+	var total int
+	sizes := make([]string, 0, len(files))
+	for _, f := range files {
+		b, err := base64.StdEncoding.DecodeString(f.Payload)
+		if err != nil {
+			return fmt.Errorf("decode %v: %v", f.Name, err)
+		}
+		total += len(b)
+		sizes = append(sizes, fmt.Sprintf("%v (%v bytes)", f.Name, len(b)))
+	}
+	if total > maxSize {
+		return validationError(fmt.Errorf("invoice size %v bytes exceeds "+
+			"the maximum of %v bytes: %v", total, maxSize,
+			strings.Join(sizes, ", ")))
+	}
+	return nil
+}
+
+// decodeInvoiceInput decodes the invoice input from the invoice.json file of
+// the passed in invoice record.
+func decodeInvoiceInput(ir v1.InvoiceRecord) (*v1.InvoiceInput, error) {
+	for _, f := range ir.Files {
+		if f.Name != "invoice.json" {
+			continue
+		}
+		b, err := base64.StdEncoding.DecodeString(f.Payload)
+		if err != nil {
+			return nil, fmt.Errorf("decode invoice.json: %v", err)
+		}
+		var invInput v1.InvoiceInput
+		err = json.Unmarshal(b, &invInput)
+		if err != nil {
+			return nil, fmt.Errorf("unmarshal invoice.json: %v", err)
+		}
+		return &invInput, nil
+	}
+	return nil, fmt.Errorf("invoice.json not found")
+}
+
+// readInvoiceCSV reads the invoice csv from the passed in file path.  A path
+// of "-" reads the csv from stdin instead so that line items can be piped in.
+// Gzip compressed csv files are decompressed transparently.
+func readInvoiceCSV(csvFile string) ([]byte, error) {
+	var (
+		b   []byte
+		err error
+	)
+	if csvFile == "-" {
+		b, err = ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, validationError(fmt.Errorf("ReadAll stdin: %v", err))
+		}
+	} else {
+		fpath := util.CleanAndExpandPath(csvFile)
+		b, err = ioutil.ReadFile(fpath)
+		if err != nil {
+			return nil, validationError(fmt.Errorf("ReadFile %v: %v", fpath,
+				err))
+		}
+	}
+
+	if strings.HasSuffix(csvFile, ".gz") || bytes.HasPrefix(b, gzipMagic) {
+		d, err := gunzip(b)
+		if err == nil {
+			return d, nil
+		}
+		// Not a valid gzip file.  Treat it as a plain csv.
+	}
+
+	return b, nil
+}
+
+// gzipMagic is the header that all gzip compressed data begins with.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// gunzip decompresses the passed in gzip data.
+func gunzip(b []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
+
+// parseDelimiter parses the passed in delimiter flag value into a single
+// rune.  The literal string "\t" is accepted as a tab character since tabs
+// are awkward to pass on the command line.
+func parseDelimiter(d string) (rune, error) {
+	if d == `\t` {
+		return '\t', nil
+	}
+	if utf8.RuneCountInString(d) != 1 {
+		return 0, fmt.Errorf("invalid delimiter '%v': must be a single "+
+			"character", d)
+	}
+	r, _ := utf8.DecodeRuneInString(d)
+	return r, nil
+}
+
+// lineItemTotals contains the totals of the line items of an invoice.
+type lineItemTotals struct {
+	laborHours  float64 // Hours of the labor line items
+	laborCost   float64 // Cost of the labor line items
+	expenseCost float64 // Cost of the expense and misc line items
+	credits     float64 // Cost of the credit line items, negative or zero
+}
+
+// total returns the invoice total.  Credits offset the total since their
+// cost is negative.
+func (t lineItemTotals) total() float64 {
+	return t.laborCost + t.expenseCost + t.credits
+}
+
+// invoiceTotals returns the labor, expense and credit totals of the passed
+// in line items.
+func invoiceTotals(lineItems []v1.LineItemsInput) lineItemTotals {
+	var t lineItemTotals
+	for _, li := range lineItems {
+		switch li.Type {
+		case v1.LineItemTypeLabor:
+			t.laborHours += li.Hours
+			t.laborCost += li.TotalCost
+		case v1.LineItemTypeExpense, v1.LineItemTypeMisc:
+			t.expenseCost += li.TotalCost
+		case v1.LineItemTypeCredit:
+			t.credits += li.TotalCost
+		}
+	}
+	return t
+}
+
+// proposalTotal contains the number of line items and the total hours and
+// cost that are billed against a proposal.
+type proposalTotal struct {
+	token     string
+	lineItems int
+	hours     float64
+	cost      float64
+}
+
+// proposalTotals returns the line item totals per proposal token, sorted by
+// token.  Line items without a proposal token are grouped under the
+// "unassigned" token, which is always last.
+func proposalTotals(lineItems []v1.LineItemsInput) []proposalTotal {
+	totals := make(map[string]*proposalTotal)
+	for _, li := range lineItems {
+		t, ok := totals[li.ProposalToken]
+		if !ok {
+			t = &proposalTotal{token: li.ProposalToken}
+			totals[li.ProposalToken] = t
+		}
+		t.lineItems++
+		t.hours += li.Hours
+		t.cost += li.TotalCost
+	}
+
+	pt := make([]proposalTotal, 0, len(totals))
+	for _, t := range totals {
+		pt = append(pt, *t)
+	}
+	sort.Slice(pt, func(i, j int) bool {
+		if pt[i].token == "" || pt[j].token == "" {
+			return pt[j].token == ""
+		}
+		return pt[i].token < pt[j].token
+	})
+	for i := range pt {
+		if pt[i].token == "" {
+			pt[i].token = "unassigned"
+		}
+	}
+
+	return pt
+}
+
+// printProposalTotals prints a table of the line item totals per proposal.
+func printProposalTotals(lineItems []v1.LineItemsInput) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "PROPOSAL\tLINE ITEMS\tHOURS\tCOST\n")
+	for _, t := range proposalTotals(lineItems) {
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\n", t.token, t.lineItems, t.hours,
+			t.cost)
+	}
+	return w.Flush()
+}
+
+// maxMonthlyLaborHours is the total number of labor hours above which an
+// invoice is considered implausible.
+const maxMonthlyLaborHours = 250
+
+// checkLaborHours returns a description of each labor line item whose hours
+// exceed the number of hours in the invoice month, and of the labor hours
+// total if it exceeds maxMonthlyLaborHours.  Sources contains the csv source
+// of each line item.
+func checkLaborHours(lineItems []v1.LineItemsInput, sources []lineItemSource, month, year int) []string {
+	first := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+	monthHours := first.AddDate(0, 1, 0).Sub(first).Hours()
+
+	var problems []string
+	for _, li := range lineItems {
+		if li.Type == v1.LineItemTypeLabor && li.Hours > monthHours {
+			problems = append(problems, fmt.Sprintf("%v: %v hours "+
+				"is more than the %v hours in the invoice month",
+				lineSource(sources, li.LineNumber), li.Hours, monthHours))
+		}
+	}
+	hours := invoiceTotals(lineItems).laborHours
+	if hours > maxMonthlyLaborHours {
+		problems = append(problems, fmt.Sprintf("total labor hours %v "+
+			"exceeds the plausible monthly maximum of %v", hours,
+			maxMonthlyLaborHours))
+	}
+	return problems
+}
+
+// checkProposalTokens fetches the proposal of each line item that specifies
+// a proposal token and returns a description of each line item whose proposal
+// does not exist, is not public or could not be fetched.
+func checkProposalTokens(lineItems []v1.LineItemsInput, sources []lineItemSource) []string {
+	// Proposal status by token so that each proposal is only
+	// fetched once.
+	type proposalStatus struct {
+		status www.PropStatusT
+		err    error // Error that prevented the proposal check
+	}
+	statuses := make(map[string]proposalStatus)
+	var problems []string
+	for _, li := range lineItems {
+		if li.ProposalToken == "" {
+			continue
+		}
+
+		ps, ok := statuses[li.ProposalToken]
+		if !ok {
+			pdr, err := client.ProposalDetails(li.ProposalToken, nil)
+			switch {
+			case proposalNotFound(err):
+				ps.status = www.PropStatusNotFound
+			case err != nil:
+				ps.err = err
+			default:
+				ps.status = pdr.Proposal.Status
+			}
+			statuses[li.ProposalToken] = ps
+		}
+
+		switch {
+		case ps.err != nil:
+			problems = append(problems, fmt.Sprintf("%v: unable to "+
+				"verify proposal %v: %v",
+				lineSource(sources, li.LineNumber), li.ProposalToken,
+				ps.err))
+		case ps.status == www.PropStatusPublic:
+			// Proposal is active
+		case ps.status == www.PropStatusNotFound:
+			problems = append(problems, fmt.Sprintf("%v: proposal %v "+
+				"not found", lineSource(sources, li.LineNumber),
+				li.ProposalToken))
+		default:
+			problems = append(problems, fmt.Sprintf("%v: proposal %v "+
+				"is not active: %v", lineSource(sources, li.LineNumber),
+				li.ProposalToken, www.PropStatus[ps.status]))
+		}
+	}
+	return problems
+}
+
+// proposalNotFound returns whether the passed in error is the proposal not
+// found error of the server.
+func proposalNotFound(err error) bool {
+	re, ok := err.(*wwwclient.ResponseError)
+	return ok && re.UserError != nil &&
+		re.UserError.ErrorCode == www.ErrorStatusProposalNotFound
+}
+
+// checkExpenseTokens returns a description of each expense and misc line item
+// that has a proposal token.  Teams that do not bill expenses against a
+// proposal use this to catch data entry mistakes.
+func checkExpenseTokens(lineItems []v1.LineItemsInput, sources []lineItemSource) []string {
+	var problems []string
+	for _, li := range lineItems {
+		if li.ProposalToken == "" {
+			continue
+		}
+		switch li.Type {
+		case v1.LineItemTypeExpense, v1.LineItemTypeMisc:
+			problems = append(problems, fmt.Sprintf("%v: %v line item "+
+				"is billed against proposal %v",
+				lineSource(sources, li.LineNumber),
+				lineItemTypeNames[li.Type], li.ProposalToken))
+		}
+	}
+	return problems
+}
+
+// checkProposalAssignments verifies that the proposal token of each labor
+// line item is the token of a proposal that the logged in user is assigned
+// to and returns a description of each line item that is not.  Politeia does
+// not track proposal assignments, so the proposals that were submitted by the
+// logged in user are used as the assigned proposals.
+func checkProposalAssignments(lineItems []v1.LineItemsInput, sources []lineItemSource) ([]string, error) {
+	lr, err := client.Me()
+	if err != nil {
+		return nil, err
+	}
+
+	// The user proposals are paginated
+	assigned := make(map[string]bool)
+	var after string
+	for {
+		upr, err := client.UserProposals(&www.UserProposals{
+			UserId: lr.UserID,
+			After:  after,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range upr.Proposals {
+			assigned[p.CensorshipRecord.Token] = true
+		}
+		if len(upr.Proposals) < www.ProposalListPageSize {
+			break
+		}
+		after = upr.Proposals[len(upr.Proposals)-1].CensorshipRecord.Token
+	}
+
+	var problems []string
+	for _, li := range lineItems {
+		if li.Type != v1.LineItemTypeLabor || li.ProposalToken == "" {
+			continue
+		}
+		if !assigned[li.ProposalToken] {
+			problems = append(problems, fmt.Sprintf("%v: labor is "+
+				"billed against proposal %v that %v is not assigned to",
+				lineSource(sources, li.LineNumber), li.ProposalToken,
+				lr.Username))
+		}
+	}
+	return problems, nil
+}
+
+// sortLineItems sorts the passed in line items by type, proposal token,
+// subtype and description and renumbers them so that invoices that contain
+// the same line items have the same invoice.json file.
+func sortLineItems(lineItems []v1.LineItemsInput) {
+	sort.SliceStable(lineItems, func(i, j int) bool {
+		a, b := lineItems[i], lineItems[j]
+		switch {
+		case a.Type != b.Type:
+			return a.Type < b.Type
+		case a.ProposalToken != b.ProposalToken:
+			return a.ProposalToken < b.ProposalToken
+		case a.Subtype != b.Subtype:
+			return a.Subtype < b.Subtype
+		}
+		return a.Description < b.Description
+	})
+	for i := range lineItems {
+		lineItems[i].LineNumber = uint16(i)
+	}
+}
+
+// lineItemTypeNames contains the csv names of the line item types.
+var lineItemTypeNames = map[v1.LineItemTypeT]string{
+	v1.LineItemTypeLabor:   "labor",
+	v1.LineItemTypeExpense: "expense",
+	v1.LineItemTypeMisc:    "misc",
+	v1.LineItemTypeCredit:  "credit",
+}
+
+// lineItemKey contains the line item fields that are used to detect
+// duplicate line items.
+type lineItemKey struct {
+	lineItemType  v1.LineItemTypeT
+	subtype       string
+	description   string
+	proposalToken string
+	hours         float64
+	totalCost     float64
+	currency      string
+	startDate     int64
+	endDate       int64
+	tags          string
+}
+
+// newLineItemKey returns the lineItemKey of the passed in line item.
+func newLineItemKey(li v1.LineItemsInput) lineItemKey {
+	return lineItemKey{
+		lineItemType:  li.Type,
+		subtype:       li.Subtype,
+		description:   li.Description,
+		proposalToken: li.ProposalToken,
+		hours:         li.Hours,
+		totalCost:     li.TotalCost,
+		currency:      li.Currency,
+		startDate:     li.StartDate,
+		endDate:       li.EndDate,
+		tags:          strings.Join(li.Tags, ";"),
+	}
+}
+
+// checkDuplicateLineItems returns an error that lists the line numbers of any
+// line items that are identical to a previous line item.  Sources contains
+// the csv source of each line item.
+func checkDuplicateLineItems(lineItems []v1.LineItemsInput, sources []lineItemSource) error {
+	seen := make(map[lineItemKey]uint16, len(lineItems))
+	var dups []string
+	for _, li := range lineItems {
+		k := newLineItemKey(li)
+		first, ok := seen[k]
+		if ok {
+			dups = append(dups, fmt.Sprintf("%v duplicates %v",
+				lineSource(sources, li.LineNumber),
+				lineSource(sources, first)))
+			continue
+		}
+		seen[k] = li.LineNumber
+	}
+	if len(dups) > 0 {
+		return validationError(fmt.Errorf("duplicate line items found: %v",
+			strings.Join(dups, ", ")))
+	}
+	return nil
+}
+
+// parseCSVError converts an error returned by cmsutil.ParseInvoiceCSV into
+// a validation error that includes the UserError context, if any, and hints
+// at the flags that relax the failed check.
+func parseCSVError(err error) error {
+	switch e := err.(type) {
+	case *cmsutil.LineItemError:
+		err = e.UserError()
+	case *cmsutil.LineItemCountError:
+		err = fmt.Errorf("%v; use --max-line-items to raise the limit", e)
+	case *csv.ParseError:
+		if e.Err == csv.ErrBareQuote || e.Err == csv.ErrQuote {
+			err = fmt.Errorf("%v; fields that contain a double quote "+
+				"must be quoted and the quote doubled (\"\"), or use "+
+				"--lazy-quotes to accept stray quotes", e)
+		}
+	}
+	if ue, ok := err.(www.UserError); ok {
+		return validationError(fmt.Errorf("Parsing CSV failed: %v: %v",
+			www.ErrorStatus[ue.ErrorCode], strings.Join(ue.ErrorContext, ", ")))
+	}
+	return validationError(fmt.Errorf("Parsing CSV failed: %v", err))
+}
+
+// parseInvoiceCSVFiles reads and parses each of the passed in csv files and
+// merges their line items into a single invoice input.  Line numbers are
+// renumbered sequentially across the files for the invoice.json file.  The
+// csv source of each line item is returned as well, which records the file
+// when there are multiple files.
+func parseInvoiceCSVFiles(csvFiles []string, opts cmsutil.Options) (*v1.InvoiceInput, []lineItemSource, error) {
+	invInput := &v1.InvoiceInput{}
+	var sources []lineItemSource
+	for _, v := range csvFiles {
+		csv, err := readInvoiceCSV(v)
+		if err != nil {
+			return nil, nil, err
+		}
+		ii, lines, err := cmsutil.ParseInvoiceCSVLines(csv, opts)
+		if err != nil {
+			if len(csvFiles) > 1 {
+				return nil, nil, validationError(fmt.Errorf("%v: %v", v,
+					parseCSVError(err)))
+			}
+			return nil, nil, parseCSVError(err)
+		}
+		invInput.CSVMetadata = ii.CSVMetadata
+		for _, li := range ii.LineItems {
+			li.LineNumber = uint16(len(invInput.LineItems))
+			invInput.LineItems = append(invInput.LineItems, li)
+		}
+		file := ""
+		if len(csvFiles) > 1 {
+			file = v
+		}
+		sources = append(sources, lineItemSources(file, lines)...)
+	}
+	if len(csvFiles) > 1 {
+		err := cmsutil.CheckLineItemCount(len(invInput.LineItems),
+			opts.MaxLineItems)
+		if err != nil {
+			return nil, nil, parseCSVError(err)
+		}
+	}
+	return invInput, sources, nil
+}
+
+// lineItemSource is the csv line that a line item was read from.
+type lineItemSource struct {
+	file string // Csv file, empty when the invoice has a single csv
+	line int    // 1-based line in the csv file
+}
+
+// String returns the line, and the file if it is known, of the source.
+func (s lineItemSource) String() string {
+	if s.file == "" {
+		return fmt.Sprintf("line %v", s.line)
+	}
+	return fmt.Sprintf("line %v of %v", s.line, s.file)
+}
+
+// lineItemSources returns the sources of the line items that were read from
+// the passed in csv file at the passed in lines, as they are returned by
+// cmsutil.ParseInvoiceCSVLines.
+func lineItemSources(file string, lines []int) []lineItemSource {
+	sources := make([]lineItemSource, 0, len(lines))
+	for _, v := range lines {
+		sources = append(sources, lineItemSource{
+			file: file,
+			line: v,
+		})
+	}
+	return sources
+}
+
+// lineSource returns the source of the line item with the passed in line
+// number.  The line number is used as the line should the source not be
+// known.
+func lineSource(sources []lineItemSource, lineNumber uint16) lineItemSource {
+	if int(lineNumber) < len(sources) {
+		return sources[lineNumber]
+	}
+	return lineItemSource{line: int(lineNumber) + 1}
+}
+
+// invoiceParseFlags contains the flags that control how the line items of an
+// invoice csv are parsed.
+type invoiceParseFlags struct {
+	delimiter       string // Line item field delimiter
+	skipHeader      bool   // Ignore the first csv record
+	fieldMapping    string // Csv column of each line item field
+	lazyQuotes      bool   // Accept stray quotes in fields
+	noTokenCheck    bool   // Skip proposal token format check
+	rate            uint   // Expected labor rate (atoms/hour)
+	precision       *uint  // Decimal places of hours and costs
+	maxLineItems    uint   // Maximum number of line items
+	validateSubtype bool   // Validate subtypes against the server
+}
+
+// options returns the cmsutil options that the line items of an invoice for
+// the passed in month and year are parsed with.
+func (f invoiceParseFlags) options(month, year int) (cmsutil.Options, error) {
+	opts := cmsutil.Options{
+		Month:          month,
+		Year:           year,
+		Rate:           f.rate,
+		SkipHeader:     f.skipHeader,
+		SkipTokenCheck: f.noTokenCheck,
+		Precision:      f.precision,
+		LazyQuotes:     f.lazyQuotes,
+		MaxLineItems:   f.maxLineItems,
+	}
+
+	var err error
+	if f.validateSubtype {
+		opts.Subtypes, err = allowedSubtypes()
+		if err != nil {
+			return opts, err
+		}
+	}
+	if f.delimiter != "" {
+		opts.Delimiter, err = parseDelimiter(f.delimiter)
+		if err != nil {
+			return opts, err
+		}
+	}
+	if f.fieldMapping != "" {
+		opts.FieldMapping, err = cmsutil.ParseFieldMapping(f.fieldMapping)
+		if err != nil {
+			return opts, validationError(err)
+		}
+	}
+
+	return opts, nil
+}
+
+// invoiceCheckFlags contains the flags of the checks that are run on the line
+// items of a parsed invoice.
+type invoiceCheckFlags struct {
+	allowDuplicates bool // Warn on duplicate line items
+	strictHours     bool // Fail on implausible labor hours
+	verifyTokens    bool // Warn on unknown or inactive proposals
+	strictTokens    bool // Fail on unknown or inactive proposals
+	expenseTokens   bool // Warn on expense/misc billed against a proposal
+	verifyAssign    bool // Warn on labor for unassigned proposals
+}
+
+// checkLineItems runs the checks that are specified by the passed in flags on
+// the line items of an invoice for the passed in month and year.  Sources
+// contains the csv source of each line item.  The problems are printed as
+// warnings to stderr.  An error is returned for the problems that the flags
+// do not allow.
+func checkLineItems(lineItems []v1.LineItemsInput, sources []lineItemSource, month, year int, f invoiceCheckFlags) error {
+	err := checkDuplicateLineItems(lineItems, sources)
+	if err != nil {
+		if !f.allowDuplicates {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+
+	// Check for implausible labor hours
+	problems := checkLaborHours(lineItems, sources, month, year)
+	for _, v := range problems {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", v)
+	}
+	if len(problems) > 0 && f.strictHours {
+		return validationError(fmt.Errorf("invoice contains implausible " +
+			"labor hours"))
+	}
+
+	// Verify the proposal tokens if specified
+	if f.verifyTokens || f.strictTokens {
+		problems := checkProposalTokens(lineItems, sources)
+		for _, v := range problems {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", v)
+		}
+		if len(problems) > 0 && f.strictTokens {
+			return validationError(fmt.Errorf("invoice contains %v invalid "+
+				"or unverified proposal token(s)", len(problems)))
+		}
+	}
+
+	// Flag expenses that are billed against a proposal if specified
+	if f.expenseTokens {
+		for _, v := range checkExpenseTokens(lineItems, sources) {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", v)
+		}
+	}
+
+	// Verify that labor is billed against assigned proposals
+	if f.verifyAssign {
+		problems, err := checkProposalAssignments(lineItems, sources)
+		if err != nil {
+			return err
+		}
+		for _, v := range problems {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", v)
+		}
+	}
+
+	return nil
+}
+
+// buildInvoiceFiles returns the files of an invoice that is made up of the
+// passed in invoice input and attachment files.  Duplicate attachment
+// filenames are renamed if specified.  An error is returned if the total size
+// of the files exceeds the passed in maximum size.
+func buildInvoiceFiles(invInput *v1.InvoiceInput, attachmentFiles []string, renameDups bool, maxSize int) ([]www.File, error) {
+	files, err := invoiceFiles(invInput, attachmentFiles)
+	if err != nil {
+		return nil, err
+	}
+	err = checkFilenames(files, renameDups)
+	if err != nil {
+		return nil, err
+	}
+	err = checkInvoiceSize(files, maxSize)
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
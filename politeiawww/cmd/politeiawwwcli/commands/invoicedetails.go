@@ -18,7 +18,7 @@ type InvoiceDetailsCmd struct {
 // Execute executes the invoice details command.
 func (cmd *InvoiceDetailsCmd) Execute(args []string) error {
 	// Get server's public key
-	vr, err := client.Version()
+	iv, err := newInvoiceVerifier()
 	if err != nil {
 		return err
 	}
@@ -30,7 +30,7 @@ func (cmd *InvoiceDetailsCmd) Execute(args []string) error {
 	}
 
 	// Verify invoice censorship record
-	err = verifyInvoice(idr.Invoice, vr.PubKey)
+	err = iv.verify(idr.Invoice)
 	if err != nil {
 		return fmt.Errorf("unable to verify invoice %v: %v",
 			idr.Invoice.CensorshipRecord.Token, err)
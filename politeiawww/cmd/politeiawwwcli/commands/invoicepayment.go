@@ -0,0 +1,109 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"fmt"
+
+	"github.com/decred/politeia/politeiawww/api/cms/v1"
+)
+
+// invoicePaymentReply is printed by the invoice payment command when raw JSON
+// output is requested.
+type invoicePaymentReply struct {
+	Token     string            `json:"token"`
+	Status    v1.InvoiceStatusT `json:"status"`
+	Timestamp int64             `json:"timestamp,omitempty"` // Timestamp of the current status
+}
+
+// InvoicePaymentCmd prints the payment status of an invoice.
+type InvoicePaymentCmd struct {
+	Args struct {
+		Token string `positional-arg-name:"token" required:"true"` // Invoice censorship token
+	} `positional-args:"true"`
+}
+
+// Execute executes the invoice payment command.
+func (cmd *InvoicePaymentCmd) Execute(args []string) error {
+	// Get server public key
+	iv, err := newInvoiceVerifier()
+	if err != nil {
+		return err
+	}
+
+	// Get invoice
+	idr, err := client.InvoiceDetails(cmd.Args.Token)
+	if err != nil {
+		return err
+	}
+
+	// Verify invoice censorship record
+	err = iv.verify(idr.Invoice)
+	if err != nil {
+		return fmt.Errorf("unable to verify invoice %v: %v",
+			idr.Invoice.CensorshipRecord.Token, err)
+	}
+
+	ir := idr.Invoice
+	ts := statusTimestamp(ir)
+	if cfg.RawJSON {
+		return printJSON(invoicePaymentReply{
+			Token:     ir.CensorshipRecord.Token,
+			Status:    ir.Status,
+			Timestamp: ts,
+		})
+	}
+
+	fmt.Printf("Invoice: %v (%02d/%v)\n", ir.CensorshipRecord.Token,
+		ir.Month, ir.Year)
+	fmt.Printf("Status : %v\n", invoiceStatuses[ir.Status])
+	if ts != 0 {
+		fmt.Printf("Since  : %v\n", formatTimestamp(ts))
+	}
+	if ir.StatusChangeReason != "" {
+		fmt.Printf("Reason : %v\n", ir.StatusChangeReason)
+	}
+
+	switch ir.Status {
+	case v1.InvoiceStatusPaid:
+		fmt.Printf("The invoice has been paid\n")
+	case v1.InvoiceStatusApproved:
+		fmt.Printf("The invoice is approved and awaiting payment\n")
+	default:
+		fmt.Printf("The invoice is not approved for payment\n")
+	}
+
+	return nil
+}
+
+// statusTimestamp returns the timestamp of the status change that set the
+// current status of the passed in invoice.  It returns 0 when the status
+// change is not known.
+func statusTimestamp(ir v1.InvoiceRecord) int64 {
+	for i := len(ir.StatusChanges) - 1; i >= 0; i-- {
+		if ir.StatusChanges[i].Status == ir.Status {
+			return ir.StatusChanges[i].Timestamp
+		}
+	}
+	return 0
+}
+
+// invoicePaymentHelpMsg is the output of the help command when
+// 'invoicepayment' is specified.
+const invoicePaymentHelpMsg = `invoicepayment "token"
+
+Print the payment status of an invoice and the time that the invoice received
+its current status. The invoice censorship record is verified before anything
+is printed. The server does not track the payout transactions, so they are not
+listed. With --json the status is printed as JSON.
+
+Arguments:
+1. token             (string, required)   Invoice censorship token
+
+Result:
+Invoice: 337fc4762dac6bbe11d3d0130f33a09978004b190e6ebbbde9312ac63f223527 (01/2019)
+Status : paid
+Since  : 2019-02-10T12:00:00Z
+The invoice has been paid`
@@ -0,0 +1,93 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/decred/politeia/politeiad/api/v1/mime"
+	www "github.com/decred/politeia/politeiawww/api/www/v1"
+)
+
+// InvoicePolicyCmd prints the server policy that applies to invoices.
+type InvoicePolicyCmd struct{}
+
+// Execute executes the invoice policy command.
+func (cmd *InvoicePolicyCmd) Execute(args []string) error {
+	source := "server"
+	pr, err := client.Policy()
+	switch {
+	case err != nil:
+		fmt.Fprintf(os.Stderr, "Unable to fetch server policy: %v\n", err)
+		pr = defaultInvoicePolicy()
+		source = "compiled-in defaults"
+	case pr.InvoiceLineItemCount == 0:
+		// The server is not running in cms mode
+		fmt.Fprintf(os.Stderr, "Server did not return an invoice policy\n")
+		pr = defaultInvoicePolicy()
+		source = "compiled-in defaults"
+	}
+
+	fmt.Printf("Policy source          : %v\n", source)
+	fmt.Printf("Max images             : %v\n", pr.MaxImages)
+	fmt.Printf("Max image size         : %v bytes\n", pr.MaxImageSize)
+	fmt.Printf("Max PDF files          : %v\n", pr.MaxPDFs)
+	fmt.Printf("Max PDF size           : %v bytes\n", pr.MaxPDFSize)
+	fmt.Printf("Max invoice size       : %v bytes\n", pr.MaxMDSize)
+	fmt.Printf("Valid MIME types       : %v\n",
+		strings.Join(pr.ValidMIMETypes, ", "))
+	fmt.Printf("Line item field count  : %v\n", pr.InvoiceLineItemCount)
+	fmt.Printf("Field delimiter        : %q\n", pr.InvoiceFieldDelimiterChar)
+	fmt.Printf("Comment character      : %q\n", pr.InvoiceCommentChar)
+	fmt.Printf("Max subtype length     : %v\n", pr.InvoiceMaxSubtypeLength)
+	fmt.Printf("Max description length : %v\n", pr.InvoiceMaxDescLength)
+
+	return nil
+}
+
+// defaultInvoicePolicy returns the invoice policy that politeiawwwcli was
+// compiled with.
+func defaultInvoicePolicy() *www.PolicyReply {
+	return &www.PolicyReply{
+		MaxImages:                 www.PolicyMaxImages,
+		MaxImageSize:              www.PolicyMaxImageSize,
+		MaxMDs:                    www.PolicyMaxMDs,
+		MaxMDSize:                 www.PolicyMaxMDSize,
+		ValidMIMETypes:            mime.ValidMimeTypes(),
+		InvoiceCommentChar:        www.PolicyInvoiceCommentChar,
+		InvoiceFieldDelimiterChar: www.PolicyInvoiceFieldDelimiterChar,
+		InvoiceLineItemCount:      www.PolicyInvoiceLineItemCount,
+		InvoiceMaxSubtypeLength:   www.PolicyInvoiceMaxSubtypeLength,
+		InvoiceMaxDescLength:      www.PolicyInvoiceMaxDescriptionLength,
+		MaxPDFs:                   www.PolicyMaxPDFs,
+		MaxPDFSize:                www.PolicyMaxPDFSize,
+	}
+}
+
+// invoicePolicyHelpMsg is the output of the help command when
+// 'invoicepolicy' is specified.
+const invoicePolicyHelpMsg = `invoicepolicy
+
+Fetch the server policy that applies to invoices and print it in a readable
+form. The policy that politeiawwwcli was compiled with is printed if the
+server policy is not available.
+
+Arguments: None
+
+Result:
+Policy source          (string)  server or compiled-in defaults
+Max images             (uint)    Maximum number of image attachments
+Max image size         (uint)    Maximum image file size (in bytes)
+Max PDF files          (uint)    Maximum number of PDF attachments
+Max PDF size           (uint)    Maximum PDF file size (in bytes)
+Max invoice size       (uint)    Maximum invoice.json file size (in bytes)
+Valid MIME types       (string)  List of acceptable MIME types
+Line item field count  (uint)    Expected number of fields per csv line
+Field delimiter        (rune)    Character that separates csv fields
+Comment character      (rune)    Character that starts a csv comment line
+Max subtype length     (uint)    Maximum length of a line item subtype
+Max description length (uint)    Maximum length of a line item description`
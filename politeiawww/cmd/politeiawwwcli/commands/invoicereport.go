@@ -0,0 +1,229 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/decred/politeia/politeiawww/api/cms/v1"
+)
+
+// InvoiceReportCmd prints the aggregate line item totals of the invoices of
+// the logged in user for a range of months.
+type InvoiceReportCmd struct {
+	From string `long:"from" optional:"true"` // First month (YYYY-MM)
+	To   string `long:"to" optional:"true"`   // Last month (YYYY-MM)
+	CSV  bool   `long:"csv" optional:"true"`  // Print the report as csv
+}
+
+// reportTotals contains the aggregate hours and cost of a group of line
+// items.
+type reportTotals struct {
+	hours float64
+	cost  float64
+}
+
+// reportGroup contains the totals of a line item group by key.
+type reportGroup struct {
+	name   string
+	totals map[string]*reportTotals
+}
+
+// add adds the passed in line item to the totals of the specified key.
+func (g *reportGroup) add(key string, li v1.LineItemsInput) {
+	t, ok := g.totals[key]
+	if !ok {
+		t = &reportTotals{}
+		g.totals[key] = t
+	}
+	t.hours += li.Hours
+	t.cost += li.TotalCost
+}
+
+// keys returns the sorted keys of the group.
+func (g *reportGroup) keys() []string {
+	keys := make([]string, 0, len(g.totals))
+	for k := range g.totals {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// parseReportMonth parses a YYYY-MM month and returns it as year*12+month-1
+// so that months can be compared.
+func parseReportMonth(s string) (int, error) {
+	t, err := time.Parse("2006-01", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid month %q: expected YYYY-MM", s)
+	}
+	return t.Year()*12 + int(t.Month()) - 1, nil
+}
+
+// Execute executes the invoice report command.
+func (cmd *InvoiceReportCmd) Execute(args []string) error {
+	// The report defaults to the current year to date
+	now := time.Now()
+	from := now.Year() * 12
+	to := now.Year()*12 + int(now.Month()) - 1
+	var err error
+	if cmd.From != "" {
+		from, err = parseReportMonth(cmd.From)
+		if err != nil {
+			return err
+		}
+	}
+	if cmd.To != "" {
+		to, err = parseReportMonth(cmd.To)
+		if err != nil {
+			return err
+		}
+	}
+	if from > to {
+		return fmt.Errorf("--from must not be after --to")
+	}
+
+	// Get server public key
+	iv, err := newInvoiceVerifier()
+	if err != nil {
+		return err
+	}
+
+	// Get user invoices
+	uir, err := client.UserInvoices(&v1.UserInvoices{})
+	if err != nil {
+		return err
+	}
+
+	groups := []*reportGroup{
+		{name: "type", totals: make(map[string]*reportTotals)},
+		{name: "proposal", totals: make(map[string]*reportTotals)},
+		{name: "month", totals: make(map[string]*reportTotals)},
+	}
+	for _, v := range uir.Invoices {
+		month := int(v.Year)*12 + int(v.Month) - 1
+		if month < from || month > to {
+			continue
+		}
+
+		err := iv.verify(v)
+		if err != nil {
+			return fmt.Errorf("unable to verify invoice %v: %v",
+				v.CensorshipRecord.Token, err)
+		}
+		invInput, err := decodeInvoiceInput(v)
+		if err != nil {
+			return fmt.Errorf("invoice %v: %v", v.CensorshipRecord.Token,
+				err)
+		}
+
+		for _, li := range invInput.LineItems {
+			// Costs in another currency cannot be added to the
+			// totals.
+			if li.Currency != "" && li.Currency != "USD" {
+				fmt.Fprintf(os.Stderr, "Warning: invoice %v line item %v "+
+					"has a cost in %v and is not included in the report\n",
+					v.CensorshipRecord.Token, li.LineNumber+1, li.Currency)
+				continue
+			}
+			proposal := li.ProposalToken
+			if proposal == "" {
+				proposal = "none"
+			}
+			groups[0].add(lineItemTypeNames[li.Type], li)
+			groups[1].add(proposal, li)
+			groups[2].add(fmt.Sprintf("%v-%02d", v.Year, v.Month), li)
+		}
+	}
+
+	if cmd.CSV {
+		return printReportCSV(groups)
+	}
+	return printReportTables(groups)
+}
+
+// printReportTables prints a table per report group.
+func printReportTables(groups []*reportGroup) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for i, g := range groups {
+		if i > 0 {
+			fmt.Fprintf(w, "\n")
+		}
+		fmt.Fprintf(w, "%v\tHOURS\tCOST\n", strings.ToUpper(g.name))
+		var total reportTotals
+		for _, k := range g.keys() {
+			t := g.totals[k]
+			fmt.Fprintf(w, "%v\t%v\t%v\n", k, t.hours, t.cost)
+			total.hours += t.hours
+			total.cost += t.cost
+		}
+		fmt.Fprintf(w, "total\t%v\t%v\n", total.hours, total.cost)
+	}
+	return w.Flush()
+}
+
+// printReportCSV prints the report groups as csv records.
+func printReportCSV(groups []*reportGroup) error {
+	w := csv.NewWriter(os.Stdout)
+	err := w.Write([]string{"group", "key", "hours", "cost"})
+	if err != nil {
+		return err
+	}
+	for _, g := range groups {
+		for _, k := range g.keys() {
+			t := g.totals[k]
+			err := w.Write([]string{
+				g.name,
+				k,
+				strconv.FormatFloat(t.hours, 'f', -1, 64),
+				strconv.FormatFloat(t.cost, 'f', -1, 64),
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// invoiceReportHelpMsg is the output of the help command when
+// 'invoicereport' is specified.
+const invoiceReportHelpMsg = `invoicereport [flags]
+
+Print the aggregate line item hours and costs of the invoices of the logged in
+user, grouped by line item type, by proposal and by month. Line items with a
+cost in another currency than USD are left out of the report.
+
+Arguments: None
+
+Flags:
+  --from             (string, optional)   First month of the report (YYYY-MM).
+                                          Defaults to January of the current
+                                          year.
+  --to               (string, optional)   Last month of the report (YYYY-MM).
+                                          Defaults to the current month.
+  --csv              (bool, optional)     Print the report as csv with the
+                                          columns group, key, hours and cost
+
+Result:
+TYPE     HOURS  COST
+labor    20     800
+expense  0      25
+total    20     825
+
+PROPOSAL  HOURS  COST
+...
+
+MONTH     HOURS  COST
+2019-01   10     425
+...`
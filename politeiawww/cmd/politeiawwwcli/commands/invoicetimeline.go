@@ -0,0 +1,106 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/decred/politeia/politeiawww/api/cms/v1"
+)
+
+// InvoiceTimelineCmd prints the status changes of an invoice in chronological
+// order.
+type InvoiceTimelineCmd struct {
+	Args struct {
+		Token string `positional-arg-name:"token" required:"true"` // Invoice censorship token
+	} `positional-args:"true"`
+}
+
+// Execute executes the invoice timeline command.
+func (cmd *InvoiceTimelineCmd) Execute(args []string) error {
+	// Get server public key
+	iv, err := newInvoiceVerifier()
+	if err != nil {
+		return err
+	}
+
+	// Get invoice
+	idr, err := client.InvoiceDetails(cmd.Args.Token)
+	if err != nil {
+		return err
+	}
+
+	// Verify invoice censorship record
+	err = iv.verify(idr.Invoice)
+	if err != nil {
+		return fmt.Errorf("unable to verify invoice %v: %v",
+			idr.Invoice.CensorshipRecord.Token, err)
+	}
+
+	ir := idr.Invoice
+	if cfg.RawJSON {
+		return printJSON(ir.StatusChanges)
+	}
+
+	if len(ir.StatusChanges) == 0 {
+		fmt.Printf("%v  %v  %v\n", formatTimestamp(ir.Timestamp),
+			invoiceStatuses[ir.Status], ir.StatusChangeReason)
+		fmt.Printf("The server does not report the status changes of the " +
+			"invoice; only the current status is shown\n")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "TIME\tSTATUS\tADMIN\tREASON\n")
+	for _, v := range ir.StatusChanges {
+		admin := v.AdminUsername
+		switch {
+		case admin != "":
+		case v.AdminPublicKey != "":
+			admin = v.AdminPublicKey
+		case v.Status == v1.InvoiceStatusNew:
+			admin = "(submitted)"
+		case v.Status == v1.InvoiceStatusWithdrawn:
+			admin = "(author)"
+		default:
+			admin = "-"
+		}
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\n", formatTimestamp(v.Timestamp),
+			invoiceStatuses[v.Status], admin, v.Reason)
+	}
+	return w.Flush()
+}
+
+// formatTimestamp formats the passed in UNIX timestamp as a UTC RFC3339 time.
+// Timestamps that are not set are formatted as a dash.
+func formatTimestamp(ts int64) string {
+	if ts == 0 {
+		return "-"
+	}
+	return time.Unix(ts, 0).UTC().Format(time.RFC3339)
+}
+
+// invoiceTimelineHelpMsg is the output of the help command when
+// 'invoicetimeline' is specified.
+const invoiceTimelineHelpMsg = `invoicetimeline "token"
+
+Print the status changes of an invoice in chronological order with the time
+of the change, the new status, the admin that made the change and the reason.
+The invoice censorship record is verified before anything is printed. Only
+the current status is printed when the server does not report the status
+changes. With --json the status changes are printed as JSON.
+
+Arguments:
+1. token             (string, required)   Invoice censorship token
+
+Result:
+TIME                  STATUS    ADMIN        REASON
+2019-02-01T10:00:00Z  new       (submitted)
+2019-02-03T15:30:00Z  disputed  admin        (string)
+2019-02-04T09:12:00Z  updated   -
+2019-02-05T11:00:00Z  approved  admin`
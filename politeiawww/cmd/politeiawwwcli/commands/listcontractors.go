@@ -0,0 +1,123 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/decred/politeia/politeiawww/api/www/v1"
+)
+
+// contractorStatuses contains the statuses that the contractors can be
+// filtered by.
+var contractorStatuses = []string{"active", "locked", "deactivated"}
+
+// contractor is a row of the contractor list.
+type contractor struct {
+	v1.AbridgedUser
+	Status string `json:"status"` // Account status
+}
+
+// ListContractorsCmd prints a table of the registered contractors.
+type ListContractorsCmd struct {
+	Status string `long:"status" optional:"true"` // Filter by account status
+}
+
+// Execute executes the list contractors command.
+func (cmd *ListContractorsCmd) Execute(args []string) error {
+	status := strings.ToLower(cmd.Status)
+	if status != "" {
+		var valid bool
+		for _, v := range contractorStatuses {
+			if status == v {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return validationError(fmt.Errorf("invalid status: %v; the "+
+				"statuses are %v", cmd.Status,
+				strings.Join(contractorStatuses, ", ")))
+		}
+	}
+
+	ur, err := client.Users(&v1.Users{})
+	if err != nil {
+		return err
+	}
+
+	contractors := make([]contractor, 0, len(ur.Users))
+	for _, v := range ur.Users {
+		// The account status is only part of the user details
+		udr, err := client.UserDetails(v.ID)
+		if err != nil {
+			return fmt.Errorf("user %v: %v", v.Username, err)
+		}
+		s := accountStatus(udr.User)
+		if status != "" && s != status {
+			continue
+		}
+		contractors = append(contractors, contractor{
+			AbridgedUser: v,
+			Status:       s,
+		})
+	}
+	sort.Slice(contractors, func(i, j int) bool {
+		return contractors[i].Username < contractors[j].Username
+	})
+
+	if cfg.RawJSON {
+		return printJSON(contractors)
+	}
+
+	return printContractorTable(contractors)
+}
+
+// accountStatus returns the account status of the passed in user.
+func accountStatus(u v1.User) string {
+	switch {
+	case u.Deactivated:
+		return "deactivated"
+	case u.Locked:
+		return "locked"
+	default:
+		return "active"
+	}
+}
+
+// printContractorTable prints a table of the passed in contractors.
+func printContractorTable(contractors []contractor) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "USERNAME\tEMAIL\tSTATUS\tID\n")
+	for _, v := range contractors {
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\n", v.Username, v.Email,
+			v.Status, v.ID)
+	}
+	return w.Flush()
+}
+
+// listContractorsHelpMsg is the output of the help command when
+// 'listcontractors' is specified.
+const listContractorsHelpMsg = `listcontractors [flags]
+
+Fetch the registered contractors and print them as a table. Requires admin
+privileges. With --json the contractors are printed as JSON. The list is capped
+at the user list page size of the server policy.
+
+Arguments: None
+
+Flags:
+  --status           (string, optional)   Only list contractors with this
+                                          account status (active, locked,
+                                          deactivated)
+
+Result:
+USERNAME  EMAIL     STATUS    ID
+(string)  (string)  (string)  (string)
+...`
@@ -0,0 +1,123 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/decred/politeia/politeiawww/api/cms/v1"
+)
+
+// invoiceStatuses contains the human readable invoice statuses.
+var invoiceStatuses = map[v1.InvoiceStatusT]string{
+	v1.InvoiceStatusInvalid:   "invalid",
+	v1.InvoiceStatusNotFound:  "not found",
+	v1.InvoiceStatusNew:       "new",
+	v1.InvoiceStatusUpdated:   "updated",
+	v1.InvoiceStatusDisputed:  "disputed",
+	v1.InvoiceStatusRejected:  "rejected",
+	v1.InvoiceStatusApproved:  "approved",
+	v1.InvoiceStatusPaid:      "paid",
+	v1.InvoiceStatusWithdrawn: "withdrawn",
+}
+
+// ListInvoicesCmd prints a table of the invoices of the logged in user.
+type ListInvoicesCmd struct {
+	Status string `long:"status" optional:"true"` // Filter by invoice status
+	Year   uint   `long:"year" optional:"true"`   // Filter by invoice year
+}
+
+// Execute executes the list invoices command.
+func (cmd *ListInvoicesCmd) Execute(args []string) error {
+	var status v1.InvoiceStatusT
+	if cmd.Status != "" {
+		for k, v := range invoiceStatuses {
+			if strings.EqualFold(cmd.Status, v) {
+				status = k
+				break
+			}
+		}
+		if status == v1.InvoiceStatusInvalid {
+			return fmt.Errorf("Invalid status: %v", cmd.Status)
+		}
+	}
+
+	// Get server public key
+	iv, err := newInvoiceVerifier()
+	if err != nil {
+		return err
+	}
+
+	// Get user invoices
+	uir, err := client.UserInvoices(&v1.UserInvoices{})
+	if err != nil {
+		return err
+	}
+
+	// Verify invoice censorship records and apply the filters
+	invoices := make([]v1.InvoiceRecord, 0, len(uir.Invoices))
+	for _, v := range uir.Invoices {
+		err := iv.verify(v)
+		if err != nil {
+			return fmt.Errorf("unable to verify invoice %v: %v",
+				v.CensorshipRecord.Token, err)
+		}
+		if status != v1.InvoiceStatusInvalid && v.Status != status {
+			continue
+		}
+		if cmd.Year != 0 && uint(v.Year) != cmd.Year {
+			continue
+		}
+		invoices = append(invoices, v)
+	}
+
+	if cfg.RawJSON {
+		return printJSON(v1.UserInvoicesReply{Invoices: invoices})
+	}
+
+	return printInvoiceTable(invoices)
+}
+
+// printInvoiceTable prints a compact table of the passed in invoices.
+func printInvoiceTable(invoices []v1.InvoiceRecord) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "TOKEN\tMONTH\tSTATUS\tHOURS\tCOST\n")
+	for _, v := range invoices {
+		hours, cost := "-", "-"
+		invInput, err := decodeInvoiceInput(v)
+		if err == nil {
+			t := invoiceTotals(invInput.LineItems)
+			hours = fmt.Sprintf("%v", t.laborHours)
+			cost = fmt.Sprintf("%v", t.total())
+		}
+		fmt.Fprintf(w, "%v\t%02d/%v\t%v\t%v\t%v\n",
+			v.CensorshipRecord.Token, v.Month, v.Year,
+			invoiceStatuses[v.Status], hours, cost)
+	}
+	return w.Flush()
+}
+
+// listInvoicesHelpMsg is the output of the help command when 'listinvoices'
+// is specified.
+const listInvoicesHelpMsg = `listinvoices [flags]
+
+Fetch the invoices of the logged in user and print them as a table. The
+table contains the labor hours and the total cost of each invoice. Credits
+are subtracted from the total cost.
+
+Arguments: None
+
+Flags:
+  --status           (string, optional)   Only list invoices with this status
+                                          (new, updated, disputed, rejected,
+                                          approved, paid)
+  --year             (uint, optional)     Only list invoices for this year
+
+Result:
+TOKEN  MONTH  STATUS  HOURS  COST
+...`
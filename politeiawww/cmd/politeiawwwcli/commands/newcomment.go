@@ -6,11 +6,12 @@ package commands
 
 import (
 	"encoding/hex"
+	"fmt"
 
 	"github.com/decred/politeia/politeiawww/api/www/v1"
 )
 
-// NewCommentCmd submits a new proposal comment.
+// NewCommentCmd submits a new proposal or invoice comment.
 type NewCommentCmd struct {
 	Args struct {
 		Token    string `positional-arg-name:"token" required:"true"`   // Censorship token
@@ -40,8 +41,14 @@ func (cmd *NewCommentCmd) Execute(args []string) error {
 		PublicKey: hex.EncodeToString(cfg.Identity.Public.Key[:]),
 	}
 
+	// Get server public key
+	vr, err := client.Version()
+	if err != nil {
+		return err
+	}
+
 	// Print request details
-	err := printJSON(nc)
+	err = printJSON(nc)
 	if err != nil {
 		return err
 	}
@@ -52,6 +59,20 @@ func (cmd *NewCommentCmd) Execute(args []string) error {
 		return err
 	}
 
+	// Verify that the server stored the comment that was signed and
+	// that the receipt was signed by the server
+	c := ncr.Comment
+	if c.Token != nc.Token || c.Comment != nc.Comment ||
+		c.Signature != nc.Signature || c.PublicKey != nc.PublicKey {
+		return fmt.Errorf("comment %v does not match the submitted comment",
+			c.CommentID)
+	}
+	err = verifyComment(c, vr.PubKey)
+	if err != nil {
+		return fmt.Errorf("unable to verify comment %v: %v", c.CommentID,
+			err)
+	}
+
 	// Print response details
 	return printJSON(ncr)
 }
@@ -60,10 +81,12 @@ func (cmd *NewCommentCmd) Execute(args []string) error {
 // specified.
 const newCommentHelpMsg = `newcomment "token" "comment"
 
-Comment on proposal as logged in user. 
+Comment on a proposal or an invoice as the logged in user. The returned comment
+is verified against the server public key: the comment signature and the
+receipt, the server signature of the comment signature, must be valid.
 
 Arguments:
-1. token       (string, required)   Proposal censorship token
+1. token       (string, required)   Proposal or invoice censorship token
 2. comment     (string, required)   Comment
 3. parentID    (string, required if replying to comment)  Id of commment
 
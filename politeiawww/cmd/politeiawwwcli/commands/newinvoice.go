@@ -5,22 +5,35 @@
 package commands
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/base64"
 	"encoding/csv"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
-	"path/filepath"
+	"math"
+	"os"
+	"os/exec"
 	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
 
-	"github.com/decred/politeia/politeiad/api/v1/mime"
+	"github.com/decred/politeia/politeiad/api/v1/identity"
 	"github.com/decred/politeia/politeiawww/api/cms/v1"
 	www "github.com/decred/politeia/politeiawww/api/www/v1"
+	wwwclient "github.com/decred/politeia/politeiawww/cmd/politeiawwwcli/client"
+	"github.com/decred/politeia/politeiawww/cmsutil"
 	"github.com/decred/politeia/util"
+	"github.com/google/uuid"
 )
 
+// invoiceMinYear is the earliest year that an invoice may be submitted for.
+const invoiceMinYear = 2015
+
 // NewInvoiceCmd submits a new invoice.
 type NewInvoiceCmd struct {
 	Args struct {
@@ -29,204 +42,1251 @@ type NewInvoiceCmd struct {
 		CSV         string   `positional-arg-name:"csvfile"`         // Invoice CSV file
 		Attachments []string `positional-arg-name:"attachmentfiles"` // Invoice attachment files
 	} `positional-args:"true" optional:"true"`
+	DryRun          bool          `long:"dryrun" optional:"true"`                // Validate invoice without submitting
+	Delimiter       string        `long:"delimiter" optional:"true"`             // Line item field delimiter
+	AllowDuplicates bool          `long:"allow-duplicates" optional:"true"`      // Warn on duplicate line items
+	Rate            uint          `long:"rate" optional:"true"`                  // Expected labor rate (atoms/hour)
+	Out             string        `long:"out" optional:"true"`                   // Save invoice.json to this path
+	Force           bool          `long:"force" optional:"true"`                 // Overwrite existing --out/--receipt files
+	VerifyTokens    bool          `long:"verify-tokens" optional:"true"`         // Warn on unknown or inactive proposals
+	StrictTokens    bool          `long:"strict-tokens" optional:"true"`         // Fail on unknown or inactive proposals
+	SkipHeader      bool          `long:"skip-header" optional:"true"`           // Ignore the first csv record
+	NoTokenCheck    bool          `long:"no-token-format-check" optional:"true"` // Skip proposal token format check
+	Quiet           bool          `long:"quiet" optional:"true"`                 // Only print the censorship token
+	MaxSize         uint          `long:"max-size" optional:"true"`              // Maximum total invoice size (bytes)
+	Identity        string        `long:"identity" optional:"true"`              // Identity file used to sign
+	Attempts        uint          `long:"attempts" optional:"true"`              // Maximum submission attempts
+	RetryTimeout    time.Duration `long:"retry-timeout" optional:"true"`         // Maximum time spent retrying
+	Receipt         string        `long:"receipt" optional:"true"`               // Save submission receipt to this path
+	Sort            bool          `long:"sort" optional:"true"`                  // Sort line items canonically
+	Month           string        `long:"month" optional:"true"`                 // Invoice month, overrides the args
+	Year            uint          `long:"year" optional:"true"`                  // Invoice year, overrides the args
+	Interactive     bool          `long:"interactive" optional:"true"`           // Prompt for the line items
+	Precision       *uint         `long:"precision" optional:"true"`             // Decimal places of hours and costs
+	StrictHours     bool          `long:"strict-hours" optional:"true"`          // Fail on implausible labor hours
+	LazyQuotes      bool          `long:"lazy-quotes" optional:"true"`           // Accept stray quotes in fields
+	AttachmentDir   string        `long:"attachment-dir" optional:"true"`        // Attach all files in this directory
+	ValidateSubtype bool          `long:"validate-subtypes" optional:"true"`     // Validate subtypes against the server
+	MaxLineItems    uint          `long:"max-line-items" optional:"true"`        // Maximum number of line items
+	Yes             bool          `long:"yes" optional:"true"`                   // Submit to mainnet without confirming
+	LogJSON         bool          `long:"log-json" optional:"true"`              // Log each step as JSON to stderr
+	VerifyAssign    bool          `long:"verify-assignment" optional:"true"`     // Warn on labor for unassigned proposals
+	TemplateFrom    string        `long:"template-from" optional:"true"`         // Prior invoice to start from
+	NoVerify        bool          `long:"no-verify" optional:"true"`             // Skip censorship record verification
+	ExpenseTokens   bool          `long:"lint-expense-tokens" optional:"true"`   // Warn on expense/misc billed against a proposal
+	Comment         string        `long:"comment" optional:"true"`               // Note for the reviewing admin
+	RenameDups      bool          `long:"rename-duplicates" optional:"true"`     // Suffix duplicate attachment filenames
+	FieldMapping    string        `long:"field-mapping" optional:"true"`         // Csv column of each line item field
+	ExchangeRate    *float64      `long:"exchangerate" optional:"true"`          // USD price of one DCR for the invoice
+	OnBehalfOf      string        `long:"on-behalf-of" optional:"true"`          // Contractor user ID (admin only)
 }
 
 // Execute executes the new invoice command.
 func (cmd *NewInvoiceCmd) Execute(args []string) error {
-	csvFile := cmd.Args.CSV
-	attachmentFiles := cmd.Args.Attachments
+	// Quiet mode silences all output other than the censorship
+	// token of the submitted invoice.  Errors are still returned.
+	if cmd.Quiet && !cfg.Silent {
+		cfg.Silent = true
+		defer func() { cfg.Silent = false }()
+	}
+
+	logger := newJSONLogger(cmd.LogJSON)
 
-	month, err := strconv.Atoi(cmd.Args.Month)
+	month, year, positional, err := cmd.invoiceDate()
 	if err != nil {
 		return err
 	}
-
-	year, err := strconv.Atoi(cmd.Args.Year)
+	err = cmd.validateFlags()
 	if err != nil {
 		return err
 	}
 
-	if csvFile == "" {
-		return errInvoiceCSVNotFound
+	// Verify that the server accepts the invoice before the invoice
+	// is parsed.  The server is not queried in dry run mode.
+	if !cmd.DryRun {
+		err = cmd.checkServer(month, year)
+		if err != nil {
+			return err
+		}
 	}
 
-	// Check for user identity
-	if cfg.Identity == nil {
-		return errUserIdentityNotFound
+	if len(positional) == 0 && !cmd.Interactive && cmd.TemplateFrom == "" {
+		return errInvoiceCSVNotFound
+	}
+	csvFile, attachmentFiles, err := cmd.invoicePaths(positional)
+	if err != nil {
+		return err
 	}
 
-	// Get server public key
-	vr, err := client.Version()
+	// The template and interactive line items are formatted using
+	// the default column order.
+	if cmd.FieldMapping != "" && (cmd.TemplateFrom != "" || csvFile == "") {
+		return validationError(fmt.Errorf("--field-mapping can only be " +
+			"used with csv files"))
+	}
+	opts, err := cmd.parseFlags().options(month, year)
 	if err != nil {
 		return err
 	}
 
-	var csv []byte
-	files := make([]www.File, 0, www.PolicyMaxImages+1)
-	// Read csv file into memory and convert to type File
-	fpath := util.CleanAndExpandPath(csvFile)
+	// Load user identity
+	id, err := loadInvoiceIdentity(cmd.Identity)
+	if err != nil {
+		return err
+	}
 
-	csv, err = ioutil.ReadFile(fpath)
+	invInput, sources, err := cmd.readInvoiceInput(csvFile, opts)
 	if err != nil {
-		return fmt.Errorf("ReadFile %v: %v", fpath, err)
+		return err
 	}
+	csvFiles := []string{"stdin"}
+	switch {
+	case cmd.TemplateFrom != "":
+		csvFiles = []string{cmd.TemplateFrom}
+	case csvFile != "":
+		csvFiles = strings.Split(csvFile, ",")
+	}
+	logger.log("parse complete", map[string]interface{}{
+		"csvfiles":  csvFiles,
+		"lineitems": len(invInput.LineItems),
+	})
 
-	invInput, err := validateParseCSV(csv)
+	err = checkLineItems(invInput.LineItems, sources, month, year,
+		cmd.checkFlags())
 	if err != nil {
-		return fmt.Errorf("Parsing CSV failed: %v", err)
+		return err
+	}
+
+	// Canonicalize the line item order if specified
+	if cmd.Sort {
+		sortLineItems(invInput.LineItems)
 	}
 
 	invInput.Month = uint16(month)
 	invInput.Year = uint16(year)
+	if cmd.ExchangeRate != nil {
+		invInput.ExchangeRate = *cmd.ExchangeRate
+	}
 
-	b, err := json.Marshal(invInput)
+	maxSize := invoiceMaxSize
+	if cmd.MaxSize != 0 {
+		maxSize = int(cmd.MaxSize)
+	}
+	files, err := buildInvoiceFiles(invInput, attachmentFiles,
+		cmd.RenameDups, maxSize)
 	if err != nil {
-		return fmt.Errorf("Marshal: %v", err)
+		return err
 	}
-
-	f := www.File{
-		Name:    "invoice.json",
-		MIME:    mime.DetectMimeType(b),
-		Digest:  hex.EncodeToString(util.Digest(b)),
-		Payload: base64.StdEncoding.EncodeToString(b),
+	if logger.enabled {
+		names := make([]string, 0, len(files))
+		for _, f := range files {
+			names = append(names, f.Name)
+		}
+		logger.log("files read", map[string]interface{}{
+			"files": names,
+		})
 	}
 
-	files = append(files, f)
-
-	// Read attachment files into memory and convert to type File
-	for _, file := range attachmentFiles {
-		path := util.CleanAndExpandPath(file)
-		attachment, err := ioutil.ReadFile(path)
+	// Save a copy of the invoice.json file if specified
+	if cmd.Out != "" {
+		err = saveInvoiceFile(files[0], cmd.Out, cmd.Force)
 		if err != nil {
-			return fmt.Errorf("ReadFile %v: %v", path, err)
+			return err
 		}
-
-		f := www.File{
-			Name:    filepath.Base(file),
-			MIME:    mime.DetectMimeType(attachment),
-			Digest:  hex.EncodeToString(util.Digest(attachment)),
-			Payload: base64.StdEncoding.EncodeToString(attachment),
-		}
-
-		files = append(files, f)
 	}
 
 	// Compute merkle root and sign it
-	sig, err := signedMerkleRoot(files, cfg.Identity)
+	sig, err := signFiles(files, id)
+	if err != nil {
+		return err
+	}
+	mr, err := merkleRoot(files)
 	if err != nil {
-		return fmt.Errorf("SignMerkleRoot: %v", err)
+		return err
 	}
+	logger.log("merkle computed", map[string]interface{}{
+		"merkle":    mr,
+		"signature": sig,
+	})
 
 	// Setup new proposal request
 	ni := &v1.NewInvoice{
-		Files:     files,
-		PublicKey: hex.EncodeToString(cfg.Identity.Public.Key[:]),
-		Signature: sig,
-		Month:     uint16(month),
-		Year:      uint16(year),
+		Files:      files,
+		PublicKey:  hex.EncodeToString(id.Public.Key[:]),
+		Signature:  sig,
+		Month:      uint16(month),
+		Year:       uint16(year),
+		Nonce:      cmsutil.InvoiceNonce(mr, uint16(month), uint16(year)),
+		OnBehalfOf: cmd.OnBehalfOf,
 	}
 
-	// Print request details
+	// Get the server version before the request details are printed
+	// so that the network that the invoice is submitted to is shown.
+	var iv *invoiceVerifier
+	if !cmd.DryRun {
+		iv, err = newInvoiceVerifier()
+		if err != nil {
+			return err
+		}
+		if !cfg.Silent && !cfg.RawJSON {
+			fmt.Printf("Network: %v (%v)\n",
+				networkName(iv.version.TestNet), cfg.Host)
+		}
+	}
+
+	// Print invoice summary and request details
+	if !cfg.Silent && !cfg.RawJSON {
+		t := invoiceTotals(invInput.LineItems)
+		fmt.Printf("Total labor hours: %v, labor cost: %v, expense/misc "+
+			"cost: %v, credits: %v, total: %v\n", t.laborHours,
+			t.laborCost, t.expenseCost, t.credits, t.total())
+		if invInput.ExchangeRate != 0 {
+			fmt.Printf("Exchange rate: %v USD/DCR\n", invInput.ExchangeRate)
+		}
+		if cmd.DryRun {
+			err = printProposalTotals(invInput.LineItems)
+			if err != nil {
+				return err
+			}
+		}
+	}
 	err = printJSON(ni)
 	if err != nil {
 		return err
 	}
 
-	// Send request
-	nir, err := client.NewInvoice(ni)
+	// Stop here if this is a dry run
+	if cmd.DryRun {
+		return nil
+	}
+
+	// Make sure the receipt can be written before the invoice is
+	// submitted.
+	if cmd.Receipt != "" && !cmd.Force {
+		path := util.CleanAndExpandPath(cmd.Receipt)
+		if _, err := os.Stat(path); err == nil {
+			return fmt.Errorf("file %v already exists; use --force to "+
+				"overwrite it", path)
+		}
+	}
+
+	// Submitting a mainnet invoice must be confirmed
+	if !iv.version.TestNet && !cmd.Yes {
+		err = confirmMainnetSubmit("invoice")
+		if err != nil {
+			return err
+		}
+	}
+
+	nir, err := cmd.submit(ni, logger)
+	if err != nil {
+		return err
+	}
+
+	// Verify the censorship record unless specified otherwise
+	err = cmd.verify(iv, ni, nir, logger)
+	if err != nil {
+		return err
+	}
+
+	// Save the submission receipt if specified
+	if cmd.Receipt != "" {
+		r := invoiceReceipt{
+			Token:           nir.CensorshipRecord.Token,
+			Merkle:          nir.CensorshipRecord.Merkle,
+			PublicKey:       ni.PublicKey,
+			Signature:       ni.Signature,
+			ServerPublicKey: iv.pubKey(),
+			ServerSignature: nir.CensorshipRecord.Signature,
+			Timestamp:       time.Now().Unix(),
+		}
+		err = saveInvoiceReceipt(r, cmd.Receipt)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Add the submission note as a comment on the invoice
+	if cmd.Comment != "" {
+		err = cmd.addComment(nir.CensorshipRecord.Token, id)
+		if err != nil {
+			return err
+		}
+	}
+
+	if cmd.Quiet {
+		fmt.Printf("%v\n", nir.CensorshipRecord.Token)
+		return nil
+	}
+
+	// Print response details
+	return printJSON(nir)
+}
+
+// parseFlags returns the csv parse flags of the new invoice command.
+func (cmd *NewInvoiceCmd) parseFlags() invoiceParseFlags {
+	return invoiceParseFlags{
+		delimiter:       cmd.Delimiter,
+		skipHeader:      cmd.SkipHeader,
+		fieldMapping:    cmd.FieldMapping,
+		lazyQuotes:      cmd.LazyQuotes,
+		noTokenCheck:    cmd.NoTokenCheck,
+		rate:            cmd.Rate,
+		precision:       cmd.Precision,
+		maxLineItems:    cmd.MaxLineItems,
+		validateSubtype: cmd.ValidateSubtype,
+	}
+}
+
+// checkFlags returns the line item check flags of the new invoice command.
+func (cmd *NewInvoiceCmd) checkFlags() invoiceCheckFlags {
+	return invoiceCheckFlags{
+		allowDuplicates: cmd.AllowDuplicates,
+		strictHours:     cmd.StrictHours,
+		verifyTokens:    cmd.VerifyTokens,
+		strictTokens:    cmd.StrictTokens,
+		expenseTokens:   cmd.ExpenseTokens,
+		verifyAssign:    cmd.VerifyAssign,
+	}
+}
+
+// invoiceDate returns the month and year of the invoice and the remaining
+// positional args.  The month and year args are optional.  If the first two
+// args are not a month and a year, all args are files and the invoice
+// defaults to the previous calendar month.  The month and year flags override
+// the args.
+func (cmd *NewInvoiceCmd) invoiceDate() (int, int, []string, error) {
+	positional := []string{cmd.Args.Month, cmd.Args.Year, cmd.Args.CSV}
+	positional = append(positional, cmd.Args.Attachments...)
+	for len(positional) > 0 && positional[len(positional)-1] == "" {
+		positional = positional[:len(positional)-1]
+	}
+	month, year := previousInvoiceMonth(time.Now())
+	if len(positional) >= 2 {
+		m, errMonth := parseInvoiceMonth(positional[0])
+		y, errYear := strconv.Atoi(positional[1])
+		if errMonth == nil && errYear == nil {
+			month, year = m, y
+			positional = positional[2:]
+		}
+	}
+
+	var err error
+	if cmd.Month != "" {
+		month, err = parseInvoiceMonth(cmd.Month)
+		if err != nil {
+			return 0, 0, nil, err
+		}
+	}
+	if cmd.Year != 0 {
+		year = int(cmd.Year)
+	}
+
+	err = validateInvoiceDate(month, year)
 	if err != nil {
+		return 0, 0, nil, err
+	}
+	return month, year, positional, nil
+}
+
+// validateFlags validates the flags whose values are submitted with the
+// invoice before anything is submitted.
+func (cmd *NewInvoiceCmd) validateFlags() error {
+	if utf8.RuneCountInString(cmd.Comment) > www.PolicyMaxCommentLength {
+		return validationError(fmt.Errorf("--comment is longer than the "+
+			"maximum of %v characters", www.PolicyMaxCommentLength))
+	}
+
+	if cmd.ExchangeRate != nil && (!(*cmd.ExchangeRate > 0) ||
+		math.IsInf(*cmd.ExchangeRate, 0)) {
+		return validationError(fmt.Errorf("--exchangerate must be a "+
+			"positive number: got %v", *cmd.ExchangeRate))
+	}
+
+	// The assignment check uses the logged in user, which is the admin
+	// and not the contractor for a proxied invoice.
+	if cmd.OnBehalfOf != "" {
+		if _, err := uuid.Parse(cmd.OnBehalfOf); err != nil {
+			return validationError(fmt.Errorf("--on-behalf-of must be "+
+				"a user ID: %v", cmd.OnBehalfOf))
+		}
+		if cmd.VerifyAssign {
+			return validationError(fmt.Errorf("--on-behalf-of can not be " +
+				"used with --verify-assignment"))
+		}
+	}
+
+	return nil
+}
+
+// checkServer verifies that the server accepts invoices for the passed in
+// month and year and, for a proxied invoice, that the logged in user is an
+// admin.
+func (cmd *NewInvoiceCmd) checkServer(month, year int) error {
+	pr, err := invoicePolicy()
+	if err != nil {
+		return err
+	}
+	err = checkInvoiceWindow(month, year, time.Now(), pr.InvoiceWindow)
+	if err != nil {
+		return err
+	}
+
+	// Only admins may submit an invoice on behalf of another user
+	if cmd.OnBehalfOf != "" {
+		lr, err := client.Me()
+		if err != nil {
+			return err
+		}
+		if !lr.IsAdmin {
+			return validationError(fmt.Errorf("--on-behalf-of requires "+
+				"an admin identity: %v is not an admin", lr.Username))
+		}
+	}
+
+	return nil
+}
+
+// invoicePaths returns the csv file and the attachment files of the invoice
+// from the passed in positional args.  The environment variables in all file
+// paths, including the path flags, are expanded.  A path that references an
+// unset variable is an error instead of silently expanding to an empty
+// string.
+func (cmd *NewInvoiceCmd) invoicePaths(positional []string) (string, []string, error) {
+	// The line items of a template invoice replace the csv file, so
+	// all args are attachments when a template is used.
+	var (
+		csvFile         string
+		attachmentFiles []string
+	)
+	switch {
+	case cmd.TemplateFrom != "":
+		attachmentFiles = positional
+	case len(positional) > 0:
+		csvFile = positional[0]
+		attachmentFiles = positional[1:]
+	}
+
+	var err error
+	if csvFile != "" {
+		csvFiles := strings.Split(csvFile, ",")
+		for i, v := range csvFiles {
+			csvFiles[i], err = expandEnvPath(v)
+			if err != nil {
+				return "", nil, err
+			}
+		}
+		csvFile = strings.Join(csvFiles, ",")
+	}
+	for i, v := range attachmentFiles {
+		attachmentFiles[i], err = expandEnvPath(v)
+		if err != nil {
+			return "", nil, err
+		}
+	}
+	paths := []*string{&cmd.AttachmentDir, &cmd.Out, &cmd.Receipt,
+		&cmd.Identity, &cmd.TemplateFrom}
+	for _, v := range paths {
+		*v, err = expandEnvPath(*v)
+		if err != nil {
+			return "", nil, err
+		}
+	}
+	if cmd.AttachmentDir != "" {
+		dirFiles, err := attachmentDirFiles(cmd.AttachmentDir,
+			attachmentFiles)
+		if err != nil {
+			return "", nil, err
+		}
+		attachmentFiles = append(attachmentFiles, dirFiles...)
+	}
+
+	return csvFile, attachmentFiles, nil
+}
+
+// readInvoiceInput reads and parses the line items of the invoice.  Multiple
+// comma separated csv files are merged into a single invoice.  The line items
+// are prompted for in interactive mode when no csv file is given.  The csv
+// source of each line item is returned as well.
+func (cmd *NewInvoiceCmd) readInvoiceInput(csvFile string, opts cmsutil.Options) (*v1.InvoiceInput, []lineItemSource, error) {
+	switch {
+	case cmd.TemplateFrom != "":
+		return invoiceFromTemplate(cmd.TemplateFrom, cmd.Interactive, opts)
+	case csvFile == "":
+		csv, err := promptInvoiceCSV(bufio.NewReader(os.Stdin), opts)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(csv) == 0 {
+			return nil, nil, validationError(fmt.Errorf("no line items " +
+				"entered"))
+		}
+		invInput, lines, err := cmsutil.ParseInvoiceCSVLines(csv, opts)
+		if err != nil {
+			return nil, nil, parseCSVError(err)
+		}
+		return invInput, lineItemSources("", lines), nil
+	default:
+		return parseInvoiceCSVFiles(strings.Split(csvFile, ","), opts)
+	}
+}
+
+// submit sends the new invoice request.  Transient errors are retried.
+func (cmd *NewInvoiceCmd) submit(ni *v1.NewInvoice, logger *jsonLogger) (*v1.NewInvoiceReply, error) {
+	attempts := defaultSubmitAttempts
+	if cmd.Attempts != 0 {
+		attempts = int(cmd.Attempts)
+	}
+	timeout := retryWindow(attempts, cfg.Timeout)
+	if cmd.RetryTimeout != 0 {
+		timeout = cmd.RetryTimeout
+		if cfg.Timeout > 0 && timeout <= cfg.Timeout {
+			fmt.Fprintf(os.Stderr, "Warning: --retry-timeout %v is not "+
+				"longer than the request timeout %v; a submission that "+
+				"times out will not be retried\n", timeout, cfg.Timeout)
+		}
+	}
+	printProgress("Submitting invoice...\n")
+	var nir *v1.NewInvoiceReply
+	var attempt int
+	n, err := retryTransient(attempts, timeout, func() error {
+		attempt++
+		logger.log("request sent", map[string]interface{}{
+			"attempt": attempt,
+		})
+		var err error
+		nir, err = client.NewInvoice(ni)
+
+		// A duplicate invoice error that references the nonce of
+		// this request means that an earlier attempt succeeded
+		// even though its reply was lost.
+		if token, ok := duplicateInvoiceToken(err); ok && attempt > 1 {
+			fmt.Fprintf(os.Stderr, "Note: invoice %v was created by an "+
+				"earlier attempt\n", token)
+			var idr *v1.InvoiceDetailsReply
+			idr, err = client.InvoiceDetails(token)
+			if err == nil {
+				nir = &v1.NewInvoiceReply{
+					CensorshipRecord: idr.Invoice.CensorshipRecord,
+				}
+			}
+		}
+
+		fields := map[string]interface{}{
+			"attempt": attempt,
+		}
+		if err != nil {
+			fields["error"] = err.Error()
+		} else {
+			fields["token"] = nir.CensorshipRecord.Token
+		}
+		logger.log("response received", fields)
 		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if n > 1 {
+		fmt.Fprintf(os.Stderr, "Note: invoice %v was submitted on attempt "+
+			"%v; the earlier attempts failed with transient errors\n",
+			nir.CensorshipRecord.Token, n)
+	}
+	return nir, nil
+}
+
+// verify verifies the censorship record of the submitted invoice unless
+// --no-verify is specified.
+func (cmd *NewInvoiceCmd) verify(iv *invoiceVerifier, ni *v1.NewInvoice, nir *v1.NewInvoiceReply, logger *jsonLogger) error {
+	if cmd.NoVerify {
+		fmt.Fprintf(os.Stderr, "Warning: the censorship record of invoice "+
+			"%v was not verified\n", nir.CensorshipRecord.Token)
+		logger.log("verification result", map[string]interface{}{
+			"token":    nir.CensorshipRecord.Token,
+			"verified": false,
+			"skipped":  true,
+		})
+		return nil
 	}
 
-	// Verify the censorship record
-	pr := www.ProposalRecord{
+	ir := v1.InvoiceRecord{
 		Files:            ni.Files,
 		PublicKey:        ni.PublicKey,
 		Signature:        ni.Signature,
 		CensorshipRecord: nir.CensorshipRecord,
 	}
-	err = verifyProposal(pr, vr.PubKey)
+	err := iv.verify(ir)
+	fields := map[string]interface{}{
+		"token":    ir.CensorshipRecord.Token,
+		"verified": err == nil,
+	}
+	if err != nil {
+		fields["error"] = err.Error()
+	}
+	logger.log("verification result", fields)
 	if err != nil {
-		return fmt.Errorf("unable to verify proposal %v: %v",
-			pr.CensorshipRecord.Token, err)
+		return fmt.Errorf("unable to verify invoice %v: %v",
+			ir.CensorshipRecord.Token, err)
 	}
+	return nil
+}
 
-	// Print response details
-	return printJSON(nir)
+// addComment adds the submission note as a comment on the invoice with the
+// passed in token.  The invoice has been submitted at this point, so a
+// failure only concerns the comment.
+func (cmd *NewInvoiceCmd) addComment(token string, id *identity.FullIdentity) error {
+	sig := id.SignMessage([]byte(token + cmd.Comment))
+	_, err := client.NewComment(&www.NewComment{
+		Token:     token,
+		Comment:   cmd.Comment,
+		Signature: hex.EncodeToString(sig[:]),
+		PublicKey: hex.EncodeToString(id.Public.Key[:]),
+	})
+	if err != nil {
+		return fmt.Errorf("invoice %v was submitted but the comment could "+
+			"not be added: %v; use newcomment to add it", token, err)
+	}
+	if !cmd.Quiet {
+		printProgress("Comment added to invoice %v\n", token)
+	}
+	return nil
 }
 
-func validateParseCSV(data []byte) (*v1.InvoiceInput, error) {
-	LineItemType := map[string]v1.LineItemTypeT{
-		"labor":   v1.LineItemTypeLabor,
-		"expense": v1.LineItemTypeExpense,
-		"misc":    v1.LineItemTypeMisc,
+// duplicateInvoiceToken returns the token of the existing invoice if the
+// passed in error is a duplicate invoice error that was returned for a
+// request with the nonce of the existing invoice.
+func duplicateInvoiceToken(err error) (string, bool) {
+	re, ok := err.(*wwwclient.ResponseError)
+	if !ok || re.UserError == nil ||
+		re.UserError.ErrorCode != www.ErrorStatusInvoiceDuplicate ||
+		len(re.UserError.ErrorContext) != 1 {
+		return "", false
 	}
-	invInput := &v1.InvoiceInput{}
+	return re.UserError.ErrorContext[0], true
+}
+
+const (
+	// defaultSubmitAttempts is the default maximum number of times
+	// that submitting an invoice is attempted.
+	defaultSubmitAttempts = 3
+
+	// defaultRetryTimeout is the default maximum amount of time spent
+	// retrying an invoice submission when the request timeout is
+	// disabled.
+	defaultRetryTimeout = time.Minute
+)
 
-	// Validate that the invoice is CSV-formatted.
-	csvReader := csv.NewReader(strings.NewReader(string(data)))
-	csvReader.Comma = www.PolicyInvoiceFieldDelimiterChar
-	csvReader.Comment = www.PolicyInvoiceCommentChar
-	csvReader.TrimLeadingSpace = true
+// retryWindow returns the default maximum amount of time spent retrying an
+// invoice submission.  The window allows each attempt to run for the full
+// request timeout plus the delays between the attempts, so that a
+// submission whose first attempt times out is still retried.
+func retryWindow(attempts int, requestTimeout time.Duration) time.Duration {
+	if requestTimeout <= 0 {
+		return defaultRetryTimeout
+	}
+	window := time.Duration(attempts) * requestTimeout
+	delay := retryInitialDelay
+	for i := 1; i < attempts; i++ {
+		window += delay
+		delay *= 2
+	}
+	return window
+}
 
-	csvFields, err := csvReader.ReadAll()
+// saveInvoiceFile writes the decoded payload of the passed in file to the
+// specified path.  The payload is the exact data that the invoice merkle root
+// is computed over.  An existing file is only overwritten if force is set.
+func saveInvoiceFile(f www.File, path string, force bool) error {
+	path = util.CleanAndExpandPath(path)
+	if _, err := os.Stat(path); err == nil && !force {
+		return fmt.Errorf("file %v already exists; use --force to "+
+			"overwrite it", path)
+	}
+
+	b, err := base64.StdEncoding.DecodeString(f.Payload)
 	if err != nil {
-		return invInput, err
+		return fmt.Errorf("decode payload for file %v: %v", f.Name, err)
 	}
 
-	lineItems := make([]v1.LineItemsInput, 0, len(csvFields))
-	// Validate that line items are the correct length and contents in
-	// field 4 and 5 are parsable to integers
-	for i, lineContents := range csvFields {
-		lineItem := v1.LineItemsInput{}
-		if len(lineContents) != www.PolicyInvoiceLineItemCount {
-			return invInput, www.UserError{
-				ErrorCode: www.ErrorStatusMalformedInvoiceFile,
-			}
+	err = ioutil.WriteFile(path, b, 0600)
+	if err != nil {
+		return fmt.Errorf("WriteFile %v: %v", path, err)
+	}
+	return nil
+}
+
+// invoiceReceipt contains the details of a successful invoice submission.
+// The server signature can be verified using the server public key, which
+// makes the receipt proof of what was submitted and when.
+type invoiceReceipt struct {
+	Token           string `json:"token"`           // Censorship token
+	Merkle          string `json:"merkle"`          // Merkle root of invoice files
+	PublicKey       string `json:"publickey"`       // User public key
+	Signature       string `json:"signature"`       // User signature of merkle root
+	ServerPublicKey string `json:"serverpublickey"` // Server public key
+	ServerSignature string `json:"serversignature"` // Server signature of merkle+token
+	Timestamp       int64  `json:"timestamp"`       // Submission time (unix)
+}
+
+// saveInvoiceReceipt writes the passed in receipt as JSON to the specified
+// path.
+func saveInvoiceReceipt(r invoiceReceipt, path string) error {
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("MarshalIndent: %v", err)
+	}
+	path = util.CleanAndExpandPath(path)
+	err = ioutil.WriteFile(path, b, 0600)
+	if err != nil {
+		return fmt.Errorf("WriteFile %v: %v", path, err)
+	}
+	return nil
+}
+
+// previousInvoiceMonth returns the month and year of the calendar month
+// before the passed in time.
+func previousInvoiceMonth(t time.Time) (int, int) {
+	prev := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location()).
+		AddDate(0, -1, 0)
+	return int(prev.Month()), prev.Year()
+}
+
+// parseInvoiceMonth parses the invoice month.  The month can be given as a
+// number or as a case insensitive full or abbreviated month name, e.g. "1",
+// "Jan" or "January".
+func parseInvoiceMonth(m string) (int, error) {
+	month, err := strconv.Atoi(m)
+	if err == nil {
+		return month, nil
+	}
+	for i := time.January; i <= time.December; i++ {
+		name := i.String()
+		if strings.EqualFold(m, name) || strings.EqualFold(m, name[:3]) {
+			return int(i), nil
+		}
+	}
+	return 0, validationError(fmt.Errorf("invalid month '%v': must be a "+
+		"number (01-12) or a month name", m))
+}
+
+// validateInvoiceDate ensures that the invoice month is between 1 and 12 and
+// that the year is between invoiceMinYear and next year.
+func validateInvoiceDate(month, year int) error {
+	if month < 1 || month > 12 {
+		return validationError(fmt.Errorf("invalid month %v: must be "+
+			"between 01 and 12", month))
+	}
+	maxYear := time.Now().Year() + 1
+	if year < invoiceMinYear || year > maxYear {
+		return validationError(fmt.Errorf("invalid year %v: must be "+
+			"between %v and %v", year, invoiceMinYear, maxYear))
+	}
+	return nil
+}
+
+// checkInvoiceWindow ensures that the invoice month and year fall within the
+// window of months that the server accepts invoices for.  No check is done
+// if the server does not advertise a window.
+func checkInvoiceWindow(month, year int, now time.Time,
+	w *www.InvoiceWindow) error {
+	if w == nil {
+		return nil
+	}
+	current := now.Year()*12 + int(now.Month()) - 1
+	first := current - int(w.MonthsBefore)
+	last := current + int(w.MonthsAfter)
+	requested := year*12 + month - 1
+	if requested >= first && requested <= last {
+		return nil
+	}
+
+	reason := "too far in the past"
+	if requested > last {
+		reason = "too far in the future"
+	}
+	return validationError(fmt.Errorf("the server does not accept invoices "+
+		"for %v-%02d: the month is %v; invoices are accepted for %v-%02d "+
+		"through %v-%02d", year, month, reason, first/12, first%12+1,
+		last/12, last%12+1))
+}
+
+// expandEnvPath expands the $VAR and ${VAR} environment variable references in
+// the passed in path.  An error is returned if a referenced variable is unset.
+// URLs are returned unchanged.
+func expandEnvPath(path string) (string, error) {
+	if strings.HasPrefix(path, "https://") ||
+		strings.HasPrefix(path, "http://") {
+		return path, nil
+	}
+	var unset []string
+	expanded := os.Expand(path, func(key string) string {
+		v, ok := os.LookupEnv(key)
+		if !ok {
+			unset = append(unset, key)
+		}
+		return v
+	})
+	if len(unset) > 0 {
+		return "", validationError(fmt.Errorf("path %v: environment "+
+			"variable %v is not set", path, strings.Join(unset, ", ")))
+	}
+	return expanded, nil
+}
+
+// invoiceFromTemplate parses the line items of a prior invoice, specified by
+// censorship token or invoice.json path, as the line items of a new invoice.
+// The line items are opened in an editor, or additional line items are
+// prompted for in interactive mode, and the result is parsed by
+// cmsutil.ParseInvoiceCSV using the month and year of the new invoice.  The
+// csv source of each line item is returned as well.
+func invoiceFromTemplate(template string, interactive bool, opts cmsutil.Options) (*v1.InvoiceInput, []lineItemSource, error) {
+	prior, err := loadInvoiceInput(template)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The line item dates fall within the month of the prior invoice
+	// so they are dropped.  The template csv is written without a
+	// header using the delimiter that it is parsed with.
+	for i := range prior.LineItems {
+		prior.LineItems[i].StartDate = 0
+		prior.LineItems[i].EndDate = 0
+	}
+	prior.CSVMetadata = nil
+	if opts.Delimiter != 0 {
+		prior.CSVMetadata = &v1.InvoiceCSVMetadata{
+			FieldDelimiterChar: opts.Delimiter,
+		}
+	}
+	opts.SkipHeader = false
+	csv, err := invoiceCSV(prior, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if interactive {
+		fmt.Printf("Line items of %v:\n%s", template, csv)
+		more, err := promptInvoiceCSV(bufio.NewReader(os.Stdin), opts)
+		if err != nil {
+			return nil, nil, err
 		}
-		hours, err := strconv.ParseFloat(lineContents[4], 64)
+		invInput, lines, err := cmsutil.ParseInvoiceCSVLines(append(csv,
+			more...), opts)
 		if err != nil {
-			return invInput, www.UserError{
-				ErrorCode: www.ErrorStatusMalformedInvoiceFile,
-			}
+			return nil, nil, parseCSVError(err)
 		}
-		cost, err := strconv.ParseFloat(lineContents[5], 64)
+		return invInput, lineItemSources("", lines), nil
+	}
+
+	c := string(www.PolicyInvoiceCommentChar)
+	header := fmt.Sprintf("%v Line items of %v. Edit them for the new "+
+		"invoice month.\n%v Lines starting with %v are ignored.\n", c,
+		template, c, c)
+	csv = append([]byte(header), csv...)
+	for {
+		csv, err = editInvoiceCSV(csv)
 		if err != nil {
-			return invInput, www.UserError{
-				ErrorCode: www.ErrorStatusMalformedInvoiceFile,
+			return nil, nil, err
+		}
+		invInput, lines, err := cmsutil.ParseInvoiceCSVLines(csv, opts)
+		if err == nil {
+			return invInput, lineItemSources("", lines), nil
+		}
+		fmt.Printf("%v\n", parseCSVError(err))
+		again, perr := promptConfirm("Edit the line items again?")
+		if perr != nil {
+			return nil, nil, perr
+		}
+		if !again {
+			return nil, nil, parseCSVError(err)
+		}
+	}
+}
+
+// editInvoiceCSV opens the passed in csv in the editor of the user and
+// returns the edited csv.  The editor is taken from $VISUAL or $EDITOR and
+// defaults to vi.
+func editInvoiceCSV(data []byte) ([]byte, error) {
+	f, err := ioutil.TempFile("", "invoice-*.csv")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f.Name())
+	_, err = f.Write(data)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	err = f.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+
+	// The editor may include arguments, e.g. "code --wait"
+	fields := strings.Fields(editor)
+	c := exec.Command(fields[0], append(fields[1:], f.Name())...)
+	c.Stdin = os.Stdin
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	err = c.Run()
+	if err != nil {
+		return nil, fmt.Errorf("editor %v: %v", editor, err)
+	}
+
+	return ioutil.ReadFile(f.Name())
+}
+
+// promptInvoiceCSV prompts for the fields of each line item and returns the
+// line items as csv that can be parsed by cmsutil.ParseInvoiceCSV.  Each line
+// item is validated as soon as it has been entered so that it can be
+// corrected.
+// An empty csv is returned when no line items are entered.
+func promptInvoiceCSV(r *bufio.Reader, opts cmsutil.Options) ([]byte, error) {
+	prompt := func(msg string) (string, error) {
+		fmt.Printf("  %v: ", msg)
+		s, err := r.ReadString('\n')
+		if err != nil && !(err == io.EOF && s != "") {
+			return "", err
+		}
+		return strings.TrimSpace(s), nil
+	}
+
+	// Single line item csvs are validated without the header
+	lineOpts := opts
+	lineOpts.SkipHeader = false
+
+	var b bytes.Buffer
+	for i := 1; ; i++ {
+		fmt.Printf("Line item %v\n", i)
+		var lineItemType string
+		for {
+			t, err := prompt("Type (labor, expense, misc, credit; empty when " +
+				"done)")
+			if err != nil {
+				return nil, err
+			}
+			if t == "" {
+				return b.Bytes(), nil
+			}
+			for _, v := range lineItemTypeNames {
+				if strings.EqualFold(t, v) {
+					lineItemType = v
+				}
 			}
+			if lineItemType != "" {
+				break
+			}
+			fmt.Printf("  Invalid type '%v'\n", t)
 		}
-		lineItem.LineNumber = uint16(i)
 
-		lineItemType, ok := LineItemType[strings.ToLower(lineContents[0])]
-		if !ok {
-			return invInput, www.UserError{
-				ErrorCode: www.ErrorStatusMalformedInvoiceFile,
+		record := []string{lineItemType}
+		for _, field := range []string{"Subtype", "Description",
+			"Proposal token (optional)", "Hours", "Cost"} {
+			v, err := prompt(field)
+			if err != nil {
+				return nil, err
 			}
+			record = append(record, v)
+		}
+
+		var line bytes.Buffer
+		w := csv.NewWriter(&line)
+		w.Comma = www.PolicyInvoiceFieldDelimiterChar
+		if opts.Delimiter != 0 {
+			w.Comma = opts.Delimiter
+		}
+		err := w.WriteAll([][]string{record})
+		if err != nil {
+			return nil, err
+		}
+		_, err = cmsutil.ParseInvoiceCSV(line.Bytes(), lineOpts)
+		if err != nil {
+			fmt.Printf("  %v; please enter the line item again\n",
+				parseCSVError(err))
+			i--
+			continue
 		}
-		lineItem.Type = lineItemType
-		lineItem.Subtype = lineContents[1]
-		lineItem.Description = lineContents[2]
-		lineItem.ProposalToken = lineContents[3]
-		lineItem.Hours = hours
-		lineItem.TotalCost = cost
-		lineItems = append(lineItems, lineItem)
+		b.Write(line.Bytes())
 	}
-	invInput.LineItems = lineItems
+}
 
-	return invInput, nil
+// csvComment is a comment or blank line of an invoice csv.  The csv reader
+// strips these lines, so they are returned separately in order for tooling
+// to be able to reattach them to the line items.
+type csvComment struct {
+	Line   int    // 1-based line number in the csv
+	Text   string // Line contents, empty for a blank line
+	Before int    // Index of the line item that follows the comment
 }
 
-const newInvoiceHelpMsg = `newinvoice [flags] "csvFile" "attachmentFiles" 
+// validateParseCSVWithComments parses the invoice csv the same way as
+// cmsutil.ParseInvoiceCSV and additionally returns the comment and blank lines that
+// the csv reader strips.
+func validateParseCSVWithComments(data []byte, opts cmsutil.Options) (*v1.InvoiceInput, []csvComment, error) {
+	invInput, err := cmsutil.ParseInvoiceCSV(data, opts)
+	if err != nil {
+		return invInput, nil, err
+	}
+	return invInput, parseCSVComments(data, opts), nil
+}
+
+// parseCSVComments returns the comment and blank lines of the passed in csv.
+// Lines that are part of a quoted field are not comments.
+func parseCSVComments(data []byte, opts cmsutil.Options) []csvComment {
+	data = cmsutil.NormalizeCSV(data)
+	var (
+		comments []csvComment
+		records  int
+		inQuote  bool
+	)
+	lines := strings.Split(string(data), "\n")
+	// A trailing newline does not start a new line
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	for i, l := range lines {
+		if !inQuote {
+			if l == "" || strings.HasPrefix(l,
+				string(www.PolicyInvoiceCommentChar)) {
+				before := records
+				if opts.SkipHeader && before > 0 {
+					before--
+				}
+				comments = append(comments, csvComment{
+					Line:   i + 1,
+					Text:   l,
+					Before: before,
+				})
+				continue
+			}
+			records++
+		}
+		if strings.Count(l, `"`)%2 == 1 {
+			inQuote = !inQuote
+		}
+	}
+	return comments
+}
+
+const newInvoiceHelpMsg = `newinvoice [flags] "month" "year" "csvFile" "attachmentFiles" 
 
 Submit a new invoice to Politeia. Invoice must be a csv file. Accepted 
-attachment filetypes: png or plain text.
+attachment filetypes: png, plain text or pdf. An invoice may have one pdf
+attachment of up to 1MB, e.g. a generated invoice summary.
+
+Each csv line contains the fields type, subtype, description, token, hours
+and cost. The type is labor, expense, misc or credit. Credit line items
+record advances and other offsets against the invoice total; their cost is
+negative or zero and they have no hours. Hours are a decimal number or a
+duration in the HH:MM format (e.g. 37:30 for 37.5 hours). Expense, misc and
+credit line items may contain an optional seventh field with the three letter
+currency code of the cost (e.g. USD). The default unit is used when it is
+omitted. The optional eighth and ninth fields contain the start and end date
+of the work (YYYY-MM-DD or RFC3339). The dates must be within the invoice
+month. Leave the currency field empty for labor line items that specify dates.
+The optional tenth field contains tags that label the line item, e.g. a sprint
+or cost center, separated by semicolons or, within a quoted field, commas.
+
+The month and year arguments are optional. The invoice is for the previous
+calendar month when they are omitted, e.g. 'newinvoice invoice.csv'.
+
+Environment variables in the csv, attachment and flag file paths are expanded,
+e.g. '$INVOICES/2019-01.csv'. The command fails if a path references a
+variable that is not set.
+
+The command exits with 2 for local validation errors, such as a malformed
+csv, 3 for identity and signing errors, 4 for network and transient server
+errors, 5 when the server rejects the invoice and 1 for any other error.
 
 Arguments:
-1. month			 (string, required)   Month (MM, 01-12)
-2. year				 (string, required)   Year (YYYY)
-3. csvFile			 (string, required)   Invoice CSV file
-4. attachmentFiles	 (string, optional)   Attachments 
+1. month			 (string, optional)   Month (MM, 01-12) or month name
+                                          (Jan, January)
+2. year				 (string, optional)   Year (YYYY)
+3. csvFile			 (string, required)   Invoice CSV file (- to read from stdin).
+                                          May be gzip compressed. Multiple
+                                          comma separated files are merged
+                                          into a single invoice.
+4. attachmentFiles	 (string, optional)   Attachments. An attachment can be a
+                                          https URL, which is downloaded with
+                                          a 30s timeout and a size limit of
+                                          the max pdf size. Plain http URLs,
+                                          including redirects to them, are
+                                          refused.
+
+Flags:
+  --dryrun           (bool, optional)     Validate and sign the invoice but do
+                                          not submit it. The line item count,
+                                          hours and cost per proposal are
+                                          printed, with line items without a
+                                          proposal token as unassigned.
+  --delimiter        (string, optional)   Line item field delimiter. Defaults
+                                          to the policy delimiter (,). Use \t
+                                          for tab separated files.
+  --allow-duplicates (bool, optional)     Warn instead of failing when two
+                                          line items are identical
+  --rate             (uint, optional)     Expected labor rate in atoms per
+                                          hour. Labor line items must cost
+                                          hours * rate.
+  --out              (string, optional)   Save the signed invoice.json to the
+                                          specified path
+  --force            (bool, optional)     Overwrite the --out and --receipt
+                                          files if they exist
+  --verify-tokens    (bool, optional)     Warn when a line item proposal token
+                                          does not exist, the proposal is not
+                                          public or the proposal could not be
+                                          fetched
+  --strict-tokens    (bool, optional)     Same as --verify-tokens but fail
+                                          instead of warning
+  --skip-header      (bool, optional)     Ignore the first line of the csv.
+                                          Use this when the csv starts with a
+                                          row of column names.
+  --no-token-format-check (bool, optional)
+                                          Do not validate that line item
+                                          proposal tokens are formatted as
+                                          censorship tokens
+  --quiet            (bool, optional)     Do not print the request details.
+                                          Only the censorship token is printed
+                                          once the invoice is submitted.
+  --max-size         (uint, optional)     Maximum total size in bytes of the
+                                          invoice csv and attachments.
+                                          Defaults to the size allowed by the
+                                          policy.
+  --identity         (string, optional)   Path of the identity file used to
+                                          sign the invoice. Defaults to the
+                                          POLITEIAWWWCLI_IDENTITY environment
+                                          variable or the logged in user's
+                                          identity.
+  --attempts         (uint, optional)     Maximum number of submission
+                                          attempts. Network errors and server
+                                          errors (5xx) are retried using
+                                          exponential backoff. A retry that
+                                          is rejected because an earlier
+                                          attempt created the invoice counts
+                                          as a success. Defaults to 3.
+  --retry-timeout    (duration, optional) Maximum time spent retrying the
+                                          submission, e.g. 5m. Defaults to
+                                          the request timeout (--timeout)
+                                          times the number of attempts plus
+                                          the delays between the attempts.
+  --receipt          (string, optional)   Save a JSON receipt containing the
+                                          token, merkle root, signatures,
+                                          server public key and submission
+                                          time to the specified path
+  --sort             (bool, optional)     Sort the line items by type, proposal
+                                          token, subtype and description before
+                                          signing so that identical invoices
+                                          have the same merkle root
+  --month            (string, optional)   Invoice month. Overrides the month
+                                          argument.
+  --year             (uint, optional)     Invoice year. Overrides the year
+                                          argument. The month and year are
+                                          checked against the range of months
+                                          that the server accepts invoices
+                                          for, if the server advertises one.
+  --interactive      (bool, optional)     Prompt for the fields of each line
+                                          item instead of reading a csv file.
+                                          Only used when no csv file is given.
+                                          With --template-from, the prompted
+                                          line items are added to the template
+                                          line items instead of opening an
+                                          editor.
+  --precision        (uint, optional)     Number of decimal places that line
+                                          item hours and costs are rounded to.
+                                          Defaults to 2.
+  --strict-hours     (bool, optional)     Fail instead of warning when a labor
+                                          line item has more hours than the
+                                          invoice month or the labor hours
+                                          total is above 250
+  --lazy-quotes      (bool, optional)     Accept stray double quotes in
+                                          unquoted and quoted fields. This
+                                          relaxes the RFC 4180 quoting rules,
+                                          so a quote in a quoted field may be
+                                          read as part of the field.
+  --attachment-dir   (string, optional)   Attach all files in the directory,
+                                          in filename order, after the listed
+                                          attachments. Subdirectories, files
+                                          with an unsupported type and files
+                                          that are already listed are skipped.
+  --validate-subtypes (bool, optional)    Validate the line item subtypes
+                                          against the allowed subtypes of the
+                                          server policy. Fails if the server
+                                          does not restrict the subtypes.
+  --max-line-items   (uint, optional)     Maximum number of line items of the
+                                          invoice. Defaults to 500.
+  --yes              (bool, optional)     Submit to a mainnet server without
+                                          asking for confirmation. Required
+                                          for mainnet submissions when stdin
+                                          is not a terminal.
+  --log-json         (bool, optional)     Write a JSON log line to stderr for
+                                          each submission step: parse
+                                          complete, files read, merkle
+                                          computed, request sent, response
+                                          received and verification result
+  --verify-assignment (bool, optional)    Warn about labor line items that
+                                          are billed against a proposal that
+                                          the logged in user is not assigned
+                                          to. Politeia does not track
+                                          assignments, so the proposals that
+                                          were submitted by the user are used.
+  --template-from    (string, optional)   Censorship token or invoice.json
+                                          path of a prior invoice whose line
+                                          items are used as the starting csv.
+                                          The line items are opened in
+                                          $VISUAL or $EDITOR (default vi) and
+                                          parsed with the new month and year
+                                          once the editor exits. Line item
+                                          dates are dropped. All arguments
+                                          after the month and year are
+                                          attachments.
+  --rename-duplicates (bool, optional)    Attach files that have the same
+                                          filename as an earlier attachment
+                                          with a numeric suffix, e.g.
+                                          receipt-1.png. Duplicate filenames
+                                          are an error otherwise.
+  --comment          (string, optional)   Note for the reviewing admin, e.g.
+                                          "includes travel reimbursement". It
+                                          is added as a comment on the invoice
+                                          once the invoice is submitted and
+                                          its censorship record is verified.
+                                          It is not sent in dry run mode.
+  --lint-expense-tokens (bool, optional)  Warn about expense and misc line
+                                          items that have a proposal token,
+                                          for teams that do not bill expenses
+                                          against a proposal
+  --no-verify        (bool, optional)     Skip the verification of the
+                                          censorship record returned by the
+                                          server, e.g. when developing against
+                                          a server with a throwaway identity.
+                                          A warning is printed instead. Only
+                                          use this against test servers.
+  --field-mapping    (string, optional)   The 0-based csv column of each line
+                                          item field for csv files that use a
+                                          different column order, e.g.
+                                          type=0,subtype=2,description=1,
+                                          token=3,hours=5,cost=4. The six
+                                          required fields must be mapped; the
+                                          currency, startdate, enddate and
+                                          tags fields are optional. Columns
+                                          that are not mapped are ignored.
+  --exchangerate     (float, optional)    The USD price of one DCR that the
+                                          invoice is billed at, e.g. 22.5. It
+                                          is stored in invoice.json, so it is
+                                          part of the signed merkle root and
+                                          the invoice records the rate for
+                                          audits. Must be positive.
+  --on-behalf-of     (string, optional)   The user ID of the contractor that
+                                          the invoice is submitted for. The
+                                          invoice is signed with the admin
+                                          identity and attributed to the
+                                          contractor. Requires admin
+                                          privileges.
 
 Result:
 {
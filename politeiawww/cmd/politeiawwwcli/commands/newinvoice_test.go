@@ -0,0 +1,637 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/decred/politeia/politeiad/api/v1/identity"
+	"github.com/decred/politeia/politeiawww/api/cms/v1"
+	www "github.com/decred/politeia/politeiawww/api/www/v1"
+	wwwclient "github.com/decred/politeia/politeiawww/cmd/politeiawwwcli/client"
+	"github.com/decred/politeia/politeiawww/cmd/politeiawwwcli/config"
+	"github.com/decred/politeia/politeiawww/cmsutil"
+)
+
+func TestInvoiceFilesOrder(t *testing.T) {
+	cfg = &config.Config{Silent: true}
+
+	dir, err := ioutil.TempDir("", "invoicefiles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Create attachments of varying sizes so that the workers
+	// finish out of order.
+	var attachments []string
+	for i := 0; i < 16; i++ {
+		path := filepath.Join(dir, fmt.Sprintf("attachment%02d.txt", i))
+		b := make([]byte, (16-i)*4096)
+		for j := range b {
+			b[j] = byte('a' + i)
+		}
+		err := ioutil.WriteFile(path, b, 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+		attachments = append(attachments, path)
+	}
+
+	invInput := &v1.InvoiceInput{
+		Month: 1,
+		Year:  2019,
+		LineItems: []v1.LineItemsInput{
+			{
+				Type:        v1.LineItemTypeLabor,
+				Subtype:     "development",
+				Description: "description",
+				Hours:       10,
+				TotalCost:   400,
+			},
+		},
+	}
+
+	var root string
+	for run := 0; run < 10; run++ {
+		files, err := invoiceFiles(invInput, attachments)
+		if err != nil {
+			t.Fatalf("invoiceFiles: %v", err)
+		}
+		if len(files) != len(attachments)+1 {
+			t.Fatalf("got %v files, want %v", len(files),
+				len(attachments)+1)
+		}
+		if files[0].Name != "invoice.json" {
+			t.Fatalf("got first file %v, want invoice.json", files[0].Name)
+		}
+		for i, path := range attachments {
+			if files[i+1].Name != filepath.Base(path) {
+				t.Fatalf("run %v: got file %v at index %v, want %v", run,
+					files[i+1].Name, i+1, filepath.Base(path))
+			}
+		}
+
+		r, err := merkleRoot(files)
+		if err != nil {
+			t.Fatalf("merkleRoot: %v", err)
+		}
+		if run > 0 && r != root {
+			t.Fatalf("run %v: got merkle root %v, want %v", run, r, root)
+		}
+		root = r
+	}
+}
+
+func TestInvoiceFilesMissingAttachment(t *testing.T) {
+	cfg = &config.Config{Silent: true}
+
+	_, err := invoiceFiles(&v1.InvoiceInput{},
+		[]string{filepath.Join(os.TempDir(), "invoicefiles-missing.png")})
+	if err == nil {
+		t.Fatal("expected error for missing attachment")
+	}
+}
+
+func TestInvoiceFilesDuplicateNames(t *testing.T) {
+	cfg = &config.Config{Silent: true}
+
+	dir, err := ioutil.TempDir("", "invoicefiles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Create two attachments with the same name in different
+	// directories.
+	var attachments []string
+	for _, v := range []string{"january", "february"} {
+		path := filepath.Join(dir, v, "receipt.txt")
+		err := os.Mkdir(filepath.Dir(path), 0700)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = ioutil.WriteFile(path, []byte(v), 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+		attachments = append(attachments, path)
+	}
+
+	files, err := invoiceFiles(&v1.InvoiceInput{}, attachments)
+	if err != nil {
+		t.Fatalf("invoiceFiles: %v", err)
+	}
+	err = checkFilenames(files, false)
+	if err == nil {
+		t.Fatal("expected error for duplicate attachment names")
+	}
+	if ExitCode(err) != ExitCodeValidation {
+		t.Fatalf("got exit code %v, want %v", ExitCode(err),
+			ExitCodeValidation)
+	}
+
+	// The duplicate is renamed when specified
+	files, err = invoiceFiles(&v1.InvoiceInput{}, attachments)
+	if err != nil {
+		t.Fatalf("invoiceFiles: %v", err)
+	}
+	err = checkFilenames(files, true)
+	if err != nil {
+		t.Fatalf("checkFilenames: %v", err)
+	}
+	want := []string{"invoice.json", "receipt.txt", "receipt-1.txt"}
+	for i, f := range files {
+		if f.Name != want[i] {
+			t.Fatalf("got file %v at index %v, want %v", f.Name, i,
+				want[i])
+		}
+	}
+}
+
+func TestExpandEnvPath(t *testing.T) {
+	os.Setenv("POLITEIA_TEST_INVOICES", "/tmp/invoices")
+	defer os.Unsetenv("POLITEIA_TEST_INVOICES")
+	os.Unsetenv("POLITEIA_TEST_UNSET")
+
+	path, err := expandEnvPath("${POLITEIA_TEST_INVOICES}/2019-01.csv")
+	if err != nil {
+		t.Fatalf("expandEnvPath: %v", err)
+	}
+	if path != "/tmp/invoices/2019-01.csv" {
+		t.Fatalf("got path %v, want /tmp/invoices/2019-01.csv", path)
+	}
+
+	_, err = expandEnvPath("$POLITEIA_TEST_UNSET/2019-01.csv")
+	if err == nil {
+		t.Fatal("expected error for unset environment variable")
+	}
+	if !strings.Contains(err.Error(), "POLITEIA_TEST_UNSET") {
+		t.Fatalf("error does not name the unset variable: %v", err)
+	}
+	if ExitCode(err) != ExitCodeValidation {
+		t.Fatalf("got exit code %v, want %v", ExitCode(err),
+			ExitCodeValidation)
+	}
+}
+
+func TestLoadInvoiceIdentityCorrupted(t *testing.T) {
+	cfg = &config.Config{Silent: true}
+
+	dir, err := ioutil.TempDir("", "identity")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	id, err := identity.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "identity.json")
+	err = id.Save(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = loadInvoiceIdentity(path)
+	if err != nil {
+		t.Fatalf("loadInvoiceIdentity: %v", err)
+	}
+
+	// Truncate the private key.  The missing bytes are zero when the
+	// identity is loaded.
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fi map[string]interface{}
+	err = json.Unmarshal(b, &fi)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fi["PrivateKey"] = fi["PrivateKey"].([]interface{})[:40]
+	b, err = json.Marshal(fi)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(path, b, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = loadInvoiceIdentity(path)
+	if err == nil {
+		t.Fatal("expected error for truncated private key")
+	}
+	if ExitCode(err) != ExitCodeIdentity {
+		t.Fatalf("got exit code %v, want %v", ExitCode(err),
+			ExitCodeIdentity)
+	}
+
+	// The logged in user identity is validated as well
+	id.Public.Key[0] ^= 0xff
+	cfg.Identity = id
+	_, err = loadInvoiceIdentity("")
+	if err == nil {
+		t.Fatal("expected error for mismatched public key")
+	}
+}
+
+func TestValidateParseCSVLineEndings(t *testing.T) {
+	lf := "# January\n" +
+		"labor,development,\"Multi\nline\",,10,400\n" +
+		"\n" +
+		"expense,hosting,Server hosting,,,25,USD,2019-01-01,2019-01-31\n"
+	fixtures := map[string]string{
+		"crlf": strings.Replace(lf, "\n", "\r\n", -1),
+		"cr":   strings.Replace(lf, "\n", "\r", -1),
+	}
+	opts := cmsutil.Options{Month: 1, Year: 2019}
+
+	want, wantComments, err := validateParseCSVWithComments([]byte(lf), opts)
+	if err != nil {
+		t.Fatalf("ParseInvoiceCSVWithComments: %v", err)
+	}
+	for name, fixture := range fixtures {
+		got, comments, err := validateParseCSVWithComments([]byte(fixture),
+			opts)
+		if err != nil {
+			t.Errorf("%v: validateParseCSVWithComments: %v", name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%v: got %+v, want %+v", name, got.LineItems,
+				want.LineItems)
+		}
+		if !reflect.DeepEqual(comments, wantComments) {
+			t.Errorf("%v: got comments %+v, want %+v", name, comments,
+				wantComments)
+		}
+		for _, li := range got.LineItems {
+			for _, f := range []string{li.Subtype, li.Description,
+				li.ProposalToken, li.Currency} {
+				if strings.Contains(f, "\r") {
+					t.Errorf("%v: line %v: field %q contains a carriage "+
+						"return", name, li.LineNumber+1, f)
+				}
+			}
+		}
+	}
+}
+
+func TestCheckDuplicateLineItemsLineNumbers(t *testing.T) {
+	csv := "labor,development,Feature,,10,400\n" +
+		"labor,development,Feature,,10,400\n"
+	tests := []struct {
+		name string
+		csv  string
+		opts cmsutil.Options
+		want string
+	}{
+		{"no header", csv, cmsutil.Options{},
+			"line 2 duplicates line 1"},
+		{"header", "type,subtype,description,token,hours,cost\n" + csv,
+			cmsutil.Options{SkipHeader: true},
+			"line 3 duplicates line 2"},
+		{"comments", "# January\n\nlabor,development,Feature,,10,400\n" +
+			"# Again\nlabor,development,Feature,,10,400\n",
+			cmsutil.Options{}, "line 5 duplicates line 3"},
+	}
+	for _, test := range tests {
+		invInput, lines, err := cmsutil.ParseInvoiceCSVLines([]byte(test.csv),
+			test.opts)
+		if err != nil {
+			t.Fatalf("%v: ParseInvoiceCSVLines: %v", test.name, err)
+		}
+		err = checkDuplicateLineItems(invInput.LineItems,
+			lineItemSources("", lines))
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%v: got error %v, want %q", test.name, err, test.want)
+		}
+	}
+}
+
+func TestParseInvoiceCSVFilesSources(t *testing.T) {
+	dir, err := ioutil.TempDir("", "invoicecsv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := []string{
+		filepath.Join(dir, "labor.csv"),
+		filepath.Join(dir, "expenses.csv"),
+	}
+	csvs := []string{
+		"type,subtype,description,token,hours,cost\n" +
+			"labor,development,Feature,,10,400\n",
+		"type,subtype,description,token,hours,cost\n# January\n" +
+			"expense,hosting,Server,,,25\nlabor,development,Feature,,10,400\n",
+	}
+	for i, v := range files {
+		err := ioutil.WriteFile(v, []byte(csvs[i]), 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	opts := cmsutil.Options{SkipHeader: true}
+	invInput, sources, err := parseInvoiceCSVFiles(files, opts)
+	if err != nil {
+		t.Fatalf("parseInvoiceCSVFiles: %v", err)
+	}
+	want := []lineItemSource{
+		{file: files[0], line: 2},
+		{file: files[1], line: 3},
+		{file: files[1], line: 4},
+	}
+	if !reflect.DeepEqual(sources, want) {
+		t.Fatalf("got sources %v, want %v", sources, want)
+	}
+
+	// The line numbers of the invoice.json file are sequential
+	for i, li := range invInput.LineItems {
+		if int(li.LineNumber) != i {
+			t.Errorf("line item %v: got line number %v", i, li.LineNumber)
+		}
+	}
+
+	err = checkDuplicateLineItems(invInput.LineItems, sources)
+	wantErr := fmt.Sprintf("line 4 of %v duplicates line 2 of %v", files[1],
+		files[0])
+	if err == nil || !strings.Contains(err.Error(), wantErr) {
+		t.Errorf("got error %v, want %q", err, wantErr)
+	}
+
+	// The file is left out for a single csv file
+	_, sources, err = parseInvoiceCSVFiles(files[1:], opts)
+	if err != nil {
+		t.Fatalf("parseInvoiceCSVFiles: %v", err)
+	}
+	if sources[0].String() != "line 3" {
+		t.Errorf("got source %v, want line 3", sources[0])
+	}
+}
+
+func TestInvoiceParseFlagsOptions(t *testing.T) {
+	f := invoiceParseFlags{
+		delimiter:  `\t`,
+		skipHeader: true,
+	}
+	opts, err := f.options(1, 2019)
+	if err != nil {
+		t.Fatalf("options: %v", err)
+	}
+	csv := "type\tsubtype\tdescription\ttoken\thours\tcost\n" +
+		"labor\tdevelopment\tFeature\t\t10\t400\n"
+	invInput, err := cmsutil.ParseInvoiceCSV([]byte(csv), opts)
+	if err != nil {
+		t.Fatalf("ParseInvoiceCSV: %v", err)
+	}
+	if len(invInput.LineItems) != 1 {
+		t.Errorf("got %v line items, want 1", len(invInput.LineItems))
+	}
+
+	f = invoiceParseFlags{
+		fieldMapping: "type=0",
+	}
+	_, err = f.options(1, 2019)
+	if err == nil {
+		t.Errorf("got nil error for an incomplete field mapping")
+	}
+}
+
+func TestCheckLineItems(t *testing.T) {
+	duplicate := "labor,development,Feature,,10,400\n" +
+		"labor,development,Feature,,10,400\n"
+	hours := "labor,development,Feature,,300,12000\n"
+	tests := []struct {
+		name    string
+		csv     string
+		flags   invoiceCheckFlags
+		wantErr bool
+	}{
+		{"duplicates", duplicate, invoiceCheckFlags{}, true},
+		{"allowed duplicates", duplicate,
+			invoiceCheckFlags{allowDuplicates: true}, false},
+		{"implausible hours", hours, invoiceCheckFlags{}, false},
+		{"strict hours", hours, invoiceCheckFlags{strictHours: true}, true},
+	}
+	for _, test := range tests {
+		opts := cmsutil.Options{Month: 1, Year: 2019}
+		invInput, lines, err := cmsutil.ParseInvoiceCSVLines([]byte(test.csv),
+			opts)
+		if err != nil {
+			t.Fatalf("%v: ParseInvoiceCSVLines: %v", test.name, err)
+		}
+		err = checkLineItems(invInput.LineItems, lineItemSources("", lines), 1,
+			2019, test.flags)
+		if (err != nil) != test.wantErr {
+			t.Errorf("%v: got error %v, want error %v", test.name, err,
+				test.wantErr)
+		}
+	}
+}
+
+func TestBatchInvoiceFlags(t *testing.T) {
+	precision := uint(4)
+	batch := BatchInvoiceCmd{
+		DryRun:          true,
+		Identity:        "id.json",
+		Delimiter:       ";",
+		SkipHeader:      true,
+		FieldMapping:    "type=0",
+		LazyQuotes:      true,
+		Rate:            40,
+		Precision:       &precision,
+		MaxLineItems:    10,
+		AllowDuplicates: true,
+		StrictHours:     true,
+		NoTokenCheck:    true,
+		VerifyTokens:    true,
+		StrictTokens:    true,
+		ValidateSubtype: true,
+		Sort:            true,
+		Attempts:        5,
+		Yes:             true,
+	}
+	ni := batch.newInvoiceCmd("01", "2019", "2019-01.csv")
+	if ni.Args.Month != "01" || ni.Args.Year != "2019" ||
+		ni.Args.CSV != "2019-01.csv" {
+		t.Fatalf("got args %+v", ni.Args)
+	}
+
+	// Every batch flag other than --continue must be passed through to
+	// the new invoice command.
+	bv := reflect.ValueOf(batch)
+	nv := reflect.ValueOf(*ni)
+	for i := 0; i < bv.NumField(); i++ {
+		name := bv.Type().Field(i).Name
+		if name == "Args" || name == "Continue" {
+			continue
+		}
+		f := nv.FieldByName(name)
+		if !f.IsValid() {
+			t.Errorf("%v: not a newinvoice flag", name)
+			continue
+		}
+		if !reflect.DeepEqual(f.Interface(), bv.Field(i).Interface()) {
+			t.Errorf("%v: got %v, want %v", name, f.Interface(),
+				bv.Field(i).Interface())
+		}
+	}
+}
+
+func TestInvoiceTotals(t *testing.T) {
+	lineItems := []v1.LineItemsInput{
+		{Type: v1.LineItemTypeLabor, Hours: 10, TotalCost: 400},
+		{Type: v1.LineItemTypeLabor, Hours: 2.5, TotalCost: 100},
+		{Type: v1.LineItemTypeExpense, TotalCost: 25},
+		{Type: v1.LineItemTypeMisc, TotalCost: 5},
+		{Type: v1.LineItemTypeCredit, TotalCost: -50},
+	}
+	want := lineItemTotals{
+		laborHours:  12.5,
+		laborCost:   500,
+		expenseCost: 30,
+		credits:     -50,
+	}
+
+	got := invoiceTotals(lineItems)
+	if got != want {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+	if got.total() != 480 {
+		t.Fatalf("got total %v, want 480", got.total())
+	}
+}
+
+func TestCheckAttachmentRedirect(t *testing.T) {
+	tests := []struct {
+		url     string
+		via     int
+		wantErr bool
+	}{
+		{"https://example.com/b.png", 1, false},
+		{"http://example.com/b.png", 1, true},
+		{"ftp://example.com/b.png", 1, true},
+		{"https://example.com/b.png", attachmentDownloadMaxRedirects, true},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, test.url, nil)
+		via := make([]*http.Request, test.via)
+		err := checkAttachmentRedirect(req, via)
+		if (err != nil) != test.wantErr {
+			t.Errorf("%v after %v redirects: got error %v, want error %v",
+				test.url, test.via, err, test.wantErr)
+		}
+	}
+}
+
+func TestRetryTransientTimeout(t *testing.T) {
+	// The first request takes longer than the request timeout.  The
+	// requests that follow are answered right away.
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			time.Sleep(300 * time.Millisecond)
+		}
+		json.NewEncoder(w).Encode(www.PolicyReply{})
+	}))
+	defer srv.Close()
+
+	cfg = &config.Config{
+		Host:    srv.URL,
+		Silent:  true,
+		Timeout: 100 * time.Millisecond,
+	}
+	var err error
+	client, err = wwwclient.New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy := func() error {
+		_, err := client.Policy()
+		return err
+	}
+
+	// A retry window that is no longer than the request timeout gives
+	// up after the first attempt times out.
+	n, err := retryTransient(3, cfg.Timeout, policy)
+	if err == nil || n != 1 {
+		t.Fatalf("got %v attempts and error %v, want 1 attempt and a "+
+			"timeout", n, err)
+	}
+
+	// The default retry window retries the attempt that timed out
+	atomic.StoreInt32(&requests, 0)
+	n, err = retryTransient(3, retryWindow(3, cfg.Timeout), policy)
+	if err != nil {
+		t.Fatalf("retryTransient: %v", err)
+	}
+	if n != 2 {
+		t.Fatalf("got %v attempts, want 2", n)
+	}
+}
+
+func TestCheckProposalTokens(t *testing.T) {
+	// The server knows the public proposal and fails for the broken
+	// one.  Any other proposal is not found.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case www.PoliteiaWWWAPIRoute + "/proposals/public":
+			json.NewEncoder(w).Encode(www.ProposalDetailsReply{
+				Proposal: www.ProposalRecord{
+					Status: www.PropStatusPublic,
+				},
+			})
+		case www.PoliteiaWWWAPIRoute + "/proposals/broken":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(www.UserError{
+				ErrorCode: www.ErrorStatusProposalNotFound,
+			})
+		}
+	}))
+	defer srv.Close()
+
+	cfg = &config.Config{
+		Host:   srv.URL,
+		Silent: true,
+	}
+	var err error
+	client, err = wwwclient.New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lineItems := []v1.LineItemsInput{
+		{LineNumber: 0, ProposalToken: "public"},
+		{LineNumber: 1, ProposalToken: "missing"},
+		{LineNumber: 2, ProposalToken: "broken"},
+	}
+	problems := checkProposalTokens(lineItems, nil)
+	if len(problems) != 2 {
+		t.Fatalf("got problems %v, want 2", problems)
+	}
+	if problems[0] != "line 2: proposal missing not found" {
+		t.Errorf("got problem %q for a missing proposal", problems[0])
+	}
+	if !strings.HasPrefix(problems[1], "line 3: unable to verify "+
+		"proposal broken: ") {
+		t.Errorf("got problem %q for a server error", problems[1])
+	}
+}
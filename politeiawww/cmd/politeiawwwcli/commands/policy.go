@@ -49,4 +49,6 @@ Response:
 	"invoicecommentchar"         (rune)     Character for comments on invoices (cmswww)
 	"invoicefielddelimiterchar"  (rune)     Charactor for invoice csv field seperation (cmswww)
 	"invoicelineitemcount"       (uint)     Expected count for line item fields (cmswww)
+	"invoicemaxsubtypelength"    (uint)     Maximum length of a line item subtype (cmswww)
+	"invoicemaxdesclength"       (uint)     Maximum length of a line item description (cmswww)
 }`
@@ -11,46 +11,75 @@ import (
 	"strings"
 
 	cms "github.com/decred/politeia/politeiawww/api/cms/v1"
+	"github.com/decred/politeia/politeiawww/cmsutil"
 )
 
+// validateInvoiceStatusTransition returns an error if an invoice is not
+// allowed to go from the old status to the new status.
+func validateInvoiceStatusTransition(oldStatus, newStatus cms.InvoiceStatusT) error {
+	if cmsutil.ValidInvoiceStatusTransition(oldStatus, newStatus) {
+		return nil
+	}
+	return fmt.Errorf("invalid status transition: invoice status cannot "+
+		"be changed from %v to %v", invoiceStatuses[oldStatus],
+		invoiceStatuses[newStatus])
+}
+
+// SetInvoiceStatusCmd sets the status of an invoice.
 type SetInvoiceStatusCmd struct {
 	Args struct {
 		Token  string `positional-arg-name:"token"`
 		Status string `positional-arg-name:"status"`
 		Reason string `positional-arg-name:"reason"`
 	} `positional-args:"true" optional:"true"`
+	Identity string `long:"identity" optional:"true"` // Identity file used to sign
 }
 
+// Execute executes the set invoice status command.
 func (cmd *SetInvoiceStatusCmd) Execute(args []string) error {
 	InvoiceStatus := map[string]cms.InvoiceStatusT{
 		"rejected": cms.InvoiceStatusRejected,
 		"approved": cms.InvoiceStatusApproved,
 		"disputed": cms.InvoiceStatusDisputed,
 	}
-	// Check for user identity
-	if cfg.Identity == nil {
-		return errUserIdentityNotFound
+	// Load user identity
+	id, err := loadInvoiceIdentity(cmd.Identity)
+	if err != nil {
+		return err
 	}
 
 	status, ok := InvoiceStatus[strings.ToLower(cmd.Args.Status)]
 	if !ok {
 		return fmt.Errorf("Invalid status: %v", cmd.Args.Status)
 	}
+	if status == cms.InvoiceStatusRejected && cmd.Args.Reason == "" {
+		return fmt.Errorf("a reason is required to reject an invoice")
+	}
+
+	// Validate the status transition against the current status
+	idr, err := client.InvoiceDetails(cmd.Args.Token)
+	if err != nil {
+		return err
+	}
+	err = validateInvoiceStatusTransition(idr.Invoice.Status, status)
+	if err != nil {
+		return err
+	}
 
 	// Setup request
-	sig := cfg.Identity.SignMessage([]byte(cmd.Args.Token +
+	sig := id.SignMessage([]byte(cmd.Args.Token +
 		strconv.Itoa(int(status)) + cmd.Args.Reason))
 
 	sis := &cms.SetInvoiceStatus{
 		Token:     cmd.Args.Token,
 		Status:    status,
 		Reason:    cmd.Args.Reason,
-		PublicKey: hex.EncodeToString(cfg.Identity.Public.Key[:]),
+		PublicKey: hex.EncodeToString(id.Public.Key[:]),
 		Signature: hex.EncodeToString(sig[:]),
 	}
 
 	// Print request details
-	err := printJSON(sis)
+	err = printJSON(sis)
 	if err != nil {
 		return err
 	}
@@ -66,14 +95,25 @@ func (cmd *SetInvoiceStatusCmd) Execute(args []string) error {
 
 // setInvoiceStatusHelpMsg is the output of the help command when
 // "setinvoicestatus" is specified.
-const setInvoiceStatusHelpMsg = `setinvoicestatus "token" "status"
+const setInvoiceStatusHelpMsg = `setinvoicestatus [flags] "token" "status" "reason"
 
-Set the status of a invoice. Requires admin privileges.
+Set the status of a invoice. Requires admin privileges. The status transition
+is validated against the current invoice status before the request is sent.
+New and updated invoices can be approved, rejected or disputed. Rejected
+invoices can be approved. A reason is required to reject an invoice.
 
 Arguments:
 1. token      (string, required)   Invoice censorship token
 2. status     (string, required)   New status (approved, disputed, rejected)
-3. message    (string)             Status change message
+3. reason     (string, optional)   Status change reason. Required for
+                                   rejected
+
+Flags:
+  --identity         (string, optional)   Path of the identity file used to
+                                          sign the status change. Defaults to
+                                          the POLITEIAWWWCLI_IDENTITY
+                                          environment variable or the logged
+                                          in user's identity.
 
 Request:
 {
@@ -20,7 +20,7 @@ type UserInvoicesCmd struct {
 // Execute executes the user invoices command.
 func (cmd *UserInvoicesCmd) Execute(args []string) error {
 	// Get server public key
-	vr, err := client.Version()
+	iv, err := newInvoiceVerifier()
 	if err != nil {
 		return err
 	}
@@ -34,7 +34,7 @@ func (cmd *UserInvoicesCmd) Execute(args []string) error {
 
 	// Verify invoice censorship records
 	for _, p := range uir.Invoices {
-		err := verifyInvoice(p, vr.PubKey)
+		err := iv.verify(p)
 		if err != nil {
 			return fmt.Errorf("unable to verify invoice %v: %v",
 				p.CensorshipRecord.Token, err)
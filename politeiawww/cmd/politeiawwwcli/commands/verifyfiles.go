@@ -0,0 +1,143 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	www "github.com/decred/politeia/politeiawww/api/www/v1"
+	"github.com/decred/politeia/util"
+)
+
+// filesBundle contains the files of a locally saved invoice or proposal.  The
+// files can either be top level, as in a newinvoice or newproposal request,
+// or nested, as in an invoicedetails or proposaldetails reply.
+type filesBundle struct {
+	Files   []www.File `json:"files"`
+	Invoice struct {
+		Files []www.File `json:"files"`
+	} `json:"invoice"`
+	Proposal struct {
+		Files []www.File `json:"files"`
+	} `json:"proposal"`
+}
+
+// VerifyFilesCmd verifies the digests of the files of an invoice or proposal.
+type VerifyFilesCmd struct {
+	Args struct {
+		Record string `positional-arg-name:"record" required:"true"` // Token or JSON file
+	} `positional-args:"true"`
+	Invoice bool `long:"invoice" optional:"true"` // Token is an invoice token
+}
+
+// Execute executes the verify files command.
+func (cmd *VerifyFilesCmd) Execute(args []string) error {
+	files, err := cmd.files()
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no files found")
+	}
+
+	var failed int
+	for _, f := range files {
+		err := verifyFileDigest(f)
+		if err != nil {
+			fmt.Printf("%v: FAIL (%v)\n", f.Name, err)
+			failed++
+			continue
+		}
+		fmt.Printf("%v: PASS\n", f.Name)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%v of %v files failed digest verification",
+			failed, len(files))
+	}
+
+	return nil
+}
+
+// files returns the files of the record specified by the command arguments.
+// The record is read from disk if it is the path to an existing file and is
+// fetched from the server otherwise.
+func (cmd *VerifyFilesCmd) files() ([]www.File, error) {
+	path := util.CleanAndExpandPath(cmd.Args.Record)
+	if _, err := os.Stat(path); err == nil {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("ReadFile %v: %v", path, err)
+		}
+		var fb filesBundle
+		err = json.Unmarshal(b, &fb)
+		if err != nil {
+			return nil, fmt.Errorf("unmarshal %v: %v", path, err)
+		}
+		switch {
+		case len(fb.Files) > 0:
+			return fb.Files, nil
+		case len(fb.Invoice.Files) > 0:
+			return fb.Invoice.Files, nil
+		}
+		return fb.Proposal.Files, nil
+	}
+
+	if cmd.Invoice {
+		idr, err := client.InvoiceDetails(cmd.Args.Record)
+		if err != nil {
+			return nil, err
+		}
+		return idr.Invoice.Files, nil
+	}
+	pdr, err := client.ProposalDetails(cmd.Args.Record, nil)
+	if err != nil {
+		return nil, err
+	}
+	return pdr.Proposal.Files, nil
+}
+
+// verifyFileDigest recomputes the digest of the file payload and verifies
+// that it matches the digest of the file.
+func verifyFileDigest(f www.File) error {
+	payload, err := base64.StdEncoding.DecodeString(f.Payload)
+	if err != nil {
+		return fmt.Errorf("invalid base64 payload: %v", err)
+	}
+	digest := hex.EncodeToString(util.Digest(payload))
+	if digest != f.Digest {
+		return fmt.Errorf("digest mismatch: got %v, want %v", digest,
+			f.Digest)
+	}
+	return nil
+}
+
+// verifyFilesHelpMsg is the output of the help command when 'verifyfiles' is
+// specified.
+const verifyFilesHelpMsg = `verifyfiles [flags] "record"
+
+Verify the digest of each file of an invoice or proposal. The digest is
+recomputed from the file payload and compared against the digest stored with
+the file. This detects corrupted or tampered payloads independent of the
+merkle root and signature.
+
+The record can be the path to a locally saved JSON file or a censorship token.
+The JSON file can be a newinvoice or newproposal request or an invoicedetails
+or proposaldetails reply. Tokens are fetched from the server as proposals
+unless --invoice is specified.
+
+Arguments:
+1. record            (string, required)   Censorship token or JSON file
+
+Flags:
+  --invoice          (bool, optional)     The token is an invoice token
+
+Result:
+invoice.json: PASS
+receipt.png: FAIL (digest mismatch: got (string), want (string))`
@@ -0,0 +1,172 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	www "github.com/decred/politeia/politeiawww/api/www/v1"
+	"github.com/decred/politeia/util"
+)
+
+// invoiceBundle contains the invoice fields that are required to verify the
+// invoice signature.  It uses the same JSON encoding as a NewInvoice request
+// so that the request printed by newinvoice can be verified.  An invoice
+// record, e.g. the invoice of an invoicedetails reply, additionally contains
+// the censorship record.
+type invoiceBundle struct {
+	Files            []www.File           `json:"files"`            // Invoice files
+	PublicKey        string               `json:"publickey"`        // Public key of the invoice author
+	Signature        string               `json:"signature"`        // Signature of the merkle root
+	CensorshipRecord www.CensorshipRecord `json:"censorshiprecord"` // Censorship record, if any
+
+	// Invoice is set when the file is an invoicedetails reply
+	Invoice *invoiceBundle `json:"invoice,omitempty"`
+}
+
+// VerifyInvoiceCmd verifies the signature of a locally saved invoice.
+type VerifyInvoiceCmd struct {
+	Args struct {
+		File string `positional-arg-name:"file" required:"true"` // Invoice bundle JSON file
+	} `positional-args:"true"`
+	ServerPubKey string `long:"server-pubkey" optional:"true"` // Server public key or key file
+	Offline      bool   `long:"offline" optional:"true"`       // Do not contact the server
+}
+
+// Execute executes the verify invoice command.
+func (cmd *VerifyInvoiceCmd) Execute(args []string) error {
+	ib, err := loadInvoiceBundle(cmd.Args.File)
+	if err != nil {
+		return err
+	}
+
+	mr, err := verifyMerkleSignature(ib.Files, ib.PublicKey, ib.Signature)
+	if mr != "" {
+		fmt.Printf("Merkle root: %v\n", mr)
+	}
+	if err != nil {
+		fmt.Printf("Signature  : FAIL\n")
+		return err
+	}
+	fmt.Printf("Signature  : PASS\n")
+
+	// Verify the censorship record if the invoice has one
+	if ib.CensorshipRecord.Token == "" {
+		return nil
+	}
+	pubKey, err := serverPublicKey(cmd.ServerPubKey, cmd.Offline)
+	if err != nil {
+		return err
+	}
+	err = verifyProposal(www.ProposalRecord{
+		Files:            ib.Files,
+		PublicKey:        ib.PublicKey,
+		Signature:        ib.Signature,
+		CensorshipRecord: ib.CensorshipRecord,
+	}, pubKey)
+	if err != nil {
+		fmt.Printf("Censorship : FAIL\n")
+		return err
+	}
+	fmt.Printf("Censorship : PASS\n")
+
+	return nil
+}
+
+// loadInvoiceBundle reads the invoice bundle JSON file at the passed in path.
+// The invoice of an invoicedetails reply is returned for an invoicedetails
+// reply file.
+func loadInvoiceBundle(path string) (*invoiceBundle, error) {
+	fpath := util.CleanAndExpandPath(path)
+	b, err := ioutil.ReadFile(fpath)
+	if err != nil {
+		return nil, fmt.Errorf("ReadFile %v: %v", fpath, err)
+	}
+
+	var ib invoiceBundle
+	err = json.Unmarshal(b, &ib)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal invoice: %v", err)
+	}
+	if ib.Invoice != nil {
+		return ib.Invoice, nil
+	}
+	return &ib, nil
+}
+
+// verifyInvoiceBundle verifies the signature of the passed in invoice bundle
+// and, if the bundle has one, its censorship record.  The server public key
+// is only requested when there is a censorship record to verify.
+func verifyInvoiceBundle(ib *invoiceBundle, serverPubKey func() (string, error)) error {
+	_, err := verifyMerkleSignature(ib.Files, ib.PublicKey, ib.Signature)
+	if err != nil {
+		return err
+	}
+	if ib.CensorshipRecord.Token == "" {
+		return nil
+	}
+	pubKey, err := serverPubKey()
+	if err != nil {
+		return err
+	}
+	return verifyProposal(www.ProposalRecord{
+		Files:            ib.Files,
+		PublicKey:        ib.PublicKey,
+		Signature:        ib.Signature,
+		CensorshipRecord: ib.CensorshipRecord,
+	}, pubKey)
+}
+
+// verifyInvoiceHelpMsg is the output of the help command when
+// 'verifyinvoice' is specified.
+const verifyInvoiceHelpMsg = `verifyinvoice "file"
+
+Verify the signature of a locally saved invoice. The merkle root of the
+invoice files is computed and the signature is verified against the public
+key. The file uses the same format as the newinvoice request, i.e. the output
+of newinvoice --dryrun. The file can also be an invoice record or an
+invoicedetails reply, in which case the censorship record is verified against
+the server public key as well.
+
+The server public key is fetched from the server unless it is supplied using
+--server-pubkey, which allows archived invoices to be verified offline.
+
+Arguments:
+1. file              (string, required)   Invoice JSON file
+
+Flags:
+  --server-pubkey    (string, optional)   Hex encoded server public key or the
+                                          path to a file that contains it,
+                                          e.g. the serverpublickey of a
+                                          newinvoice --receipt
+  --offline          (bool, optional)     Never contact the server. Requires
+                                          --server-pubkey to verify a
+                                          censorship record
+
+File format:
+{
+  "files": [
+    {
+      "name":      (string)  Filename 
+      "mime":      (string)  Mime type 
+      "digest":    (string)  File digest 
+      "payload":   (string)  File payload 
+    }
+  ],
+  "publickey":   (string)  Public key of user
+  "signature":   (string)  Signed merkel root of files in invoice
+  "censorshiprecord": {    (optional)
+    "token":       (string)  Censorship token
+    "merkle":      (string)  Merkle root of invoice
+    "signature":   (string)  Server side signature of []byte(Merkle+Token)
+  }
+}
+
+Result:
+Merkle root: (string)  Computed merkle root of the invoice files
+Signature  : (string)  PASS or FAIL
+Censorship : (string)  PASS or FAIL, only if there is a censorship record`
@@ -0,0 +1,146 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package commands
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/decred/politeia/util"
+)
+
+// VerifyInvoicesCmd verifies the signatures and censorship records of a
+// directory of locally saved invoices.
+type VerifyInvoicesCmd struct {
+	Args struct {
+		Dir string `positional-arg-name:"dir" required:"true"` // Directory of invoice JSON files
+	} `positional-args:"true"`
+	ServerPubKey string `long:"server-pubkey" optional:"true"` // Server public key or key file
+	Offline      bool   `long:"offline" optional:"true"`       // Do not contact the server
+	Workers      uint   `long:"workers" optional:"true"`       // Number of concurrent verifications
+}
+
+// Execute executes the verify invoices command.
+func (cmd *VerifyInvoicesCmd) Execute(args []string) error {
+	dir := util.CleanAndExpandPath(cmd.Args.Dir)
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("ReadDir %v: %v", dir, err)
+	}
+
+	// ReadDir returns the entries sorted by filename
+	var paths []string
+	for _, fi := range fis {
+		if !fi.Mode().IsRegular() ||
+			!strings.EqualFold(filepath.Ext(fi.Name()), ".json") {
+			continue
+		}
+		paths = append(paths, filepath.Join(dir, fi.Name()))
+	}
+	if len(paths) == 0 {
+		return fmt.Errorf("no invoice JSON files found in %v", dir)
+	}
+
+	// The server public key is only fetched once, and only if one of
+	// the invoices has a censorship record.
+	var (
+		once      sync.Once
+		pubKey    string
+		pubKeyErr error
+	)
+	serverPubKey := func() (string, error) {
+		once.Do(func() {
+			pubKey, pubKeyErr = serverPublicKey(cmd.ServerPubKey,
+				cmd.Offline)
+		})
+		return pubKey, pubKeyErr
+	}
+
+	workers := runtime.NumCPU()
+	if cmd.Workers != 0 {
+		workers = int(cmd.Workers)
+	}
+	if workers > len(paths) {
+		workers = len(paths)
+	}
+
+	errs := make([]error, len(paths))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				ib, err := loadInvoiceBundle(paths[j])
+				if err != nil {
+					errs[j] = err
+					continue
+				}
+				errs[j] = verifyInvoiceBundle(ib, serverPubKey)
+			}
+		}()
+	}
+	for i := range paths {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	// Print the results in filename order
+	var failed int
+	for i, path := range paths {
+		name := filepath.Base(path)
+		if errs[i] != nil {
+			fmt.Printf("%v: FAIL (%v)\n", name, errs[i])
+			failed++
+			continue
+		}
+		fmt.Printf("%v: PASS\n", name)
+	}
+	fmt.Printf("%v of %v invoices passed verification\n",
+		len(paths)-failed, len(paths))
+	if failed > 0 {
+		return fmt.Errorf("%v of %v invoices failed verification", failed,
+			len(paths))
+	}
+
+	return nil
+}
+
+// verifyInvoicesHelpMsg is the output of the help command when
+// 'verifyinvoices' is specified.
+const verifyInvoicesHelpMsg = `verifyinvoices [flags] "dir"
+
+Verify each invoice JSON file in a directory, e.g. a directory of archived
+invoices. Each file is verified the same way as by verifyinvoice: the merkle
+root and signature are always verified and the censorship record is verified
+against the server public key when the file has one. The invoices are
+verified concurrently. The command fails if any invoice fails verification.
+
+The server public key is fetched from the server once, and only if one of the
+invoices has a censorship record, unless it is supplied using --server-pubkey.
+
+Arguments:
+1. dir               (string, required)   Directory of invoice JSON files
+
+Flags:
+  --server-pubkey    (string, optional)   Hex encoded server public key or the
+                                          path to a file that contains it
+  --offline          (bool, optional)     Never contact the server. Requires
+                                          --server-pubkey to verify censorship
+                                          records
+  --workers          (uint, optional)     Number of invoices that are verified
+                                          concurrently. Defaults to the number
+                                          of CPUs.
+
+Result:
+2019-01.json: PASS
+2019-02.json: FAIL (invalid signature)
+1 of 2 invoices passed verification`
@@ -8,6 +8,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/decred/dcrd/chaincfg/chainhash"
 	"github.com/decred/dcrwallet/rpc/walletrpc"
@@ -62,22 +63,35 @@ func (cmd *VoteCmd) Execute(args []string) error {
 		}
 	}
 
+	// The proposal is not part of the active votes when voting has
+	// not been started or has already finished.  Lookup the vote
+	// status so that the user knows why the vote can't be cast.
 	if pvt.Proposal.Name == "" {
-		return fmt.Errorf("proposal not found: %v", token)
+		vsr, err := client.VoteStatus(token)
+		if err != nil || vsr.Status == v1.PropVoteStatusDoesntExist {
+			return fmt.Errorf("proposal not found: %v", token)
+		}
+		return fmt.Errorf("voting is not active on proposal %v: %v", token,
+			v1.PropVoteStatus[vsr.Status])
 	}
 
 	// Ensure that the passed in voteID is one of the
 	// proposal's voting options and save the vote bits
-	var voteBits string
+	var (
+		voteBits string
+		options  = make([]string, 0, len(pvt.StartVote.Vote.Options))
+	)
 	for _, option := range pvt.StartVote.Vote.Options {
-		if voteID == option.Id {
+		if strings.EqualFold(voteID, option.Id) {
 			voteBits = strconv.FormatUint(option.Bits, 16)
 			break
 		}
+		options = append(options, option.Id)
 	}
 
 	if voteBits == "" {
-		return fmt.Errorf("vote id not found: %v", voteID)
+		return fmt.Errorf("vote id not found: %v; the vote options are %v",
+			voteID, strings.Join(options, ", "))
 	}
 
 	// Find user's tickets that are eligible to vote on this
@@ -137,23 +151,35 @@ func (cmd *VoteCmd) Execute(args []string) error {
 		return fmt.Errorf("SignMessages: %v", err)
 	}
 
-	// Validate signatures
-	for i, r := range sigs.Replies {
-		if r.Error != "" {
-			return fmt.Errorf("signature failed index %v: %v", i, r.Error)
-		}
-	}
-
-	// Setup cast votes request
-	votes := make([]v1.CastVote, 0, len(eligibleTickets))
+	// Setup cast votes request.  Tickets that the wallet failed to
+	// sign for are not cast and are reported as failed votes.
+	var (
+		votes          = make([]v1.CastVote, 0, len(eligibleTickets))
+		castTickets    = make([]string, 0, len(eligibleTickets))
+		failedTickets  = make([]string, 0, len(eligibleTickets))
+		failedReceipts = make([]v1.CastVoteReply, 0, len(eligibleTickets))
+	)
 	for i, ticket := range eligibleTickets {
 		// eligibleTickets and sigs use the same index
+		r := sigs.Replies[i]
+		if r.Error != "" {
+			failedTickets = append(failedTickets, ticket)
+			failedReceipts = append(failedReceipts, v1.CastVoteReply{
+				Error: "signature failed: " + r.Error,
+			})
+			continue
+		}
 		votes = append(votes, v1.CastVote{
 			Token:     token,
 			Ticket:    ticket,
 			VoteBit:   voteBits,
-			Signature: hex.EncodeToString(sigs.Replies[i].Signature),
+			Signature: hex.EncodeToString(r.Signature),
 		})
+		castTickets = append(castTickets, ticket)
+	}
+	if len(votes) == 0 {
+		return fmt.Errorf("the wallet could not sign the vote for any of "+
+			"the %v eligible tickets", len(eligibleTickets))
 	}
 
 	// Cast proposal votes
@@ -168,12 +194,11 @@ func (cmd *VoteCmd) Execute(args []string) error {
 	// the ticket hash so in order to associate a failed
 	// receipt with a specific ticket, we need  to lookup the
 	// ticket hash and store it separately.
-	failedReceipts := make([]v1.CastVoteReply, 0, len(br.Receipts))
-	failedTickets := make([]string, 0, len(eligibleTickets))
+	var succeeded int
 	for i, v := range br.Receipts {
 		// Lookup ticket hash
-		// br.Receipts and eligibleTickets use the same index
-		h := eligibleTickets[i]
+		// br.Receipts and castTickets use the same index
+		h := castTickets[i]
 
 		// Check for voting error
 		if v.Error != "" {
@@ -195,12 +220,16 @@ func (cmd *VoteCmd) Execute(args []string) error {
 			v.Error = "Could not verify receipt " + v.ClientSignature
 			failedReceipts = append(failedReceipts, v)
 			failedTickets = append(failedTickets, h)
+			continue
 		}
+
+		succeeded++
 	}
 
 	// Print results
 	if !cfg.Silent {
-		fmt.Printf("Votes succeeded: %v\n", len(br.Receipts)-len(failedReceipts))
+		fmt.Printf("Eligible tickets: %v\n", len(eligibleTickets))
+		fmt.Printf("Votes succeeded: %v\n", succeeded)
 		fmt.Printf("Votes failed   : %v\n", len(failedReceipts))
 		for i, v := range failedReceipts {
 			fmt.Printf("Failed vote    : %v %v\n", failedTickets[i], v.Error)
@@ -213,13 +242,21 @@ func (cmd *VoteCmd) Execute(args []string) error {
 // voteHelpMsg is the output of the help command when 'vote' is specified.
 const voteHelpMsg = `vote "token" "voteid"
 
-Cast ticket votes for a proposal.
+Cast ticket votes for a proposal. The tickets in the wallet that are eligible
+to vote on the proposal are looked up, each ticket vote is signed by the wallet
+and the ballot is cast. The vote receipts are verified against the server
+public key. A ticket vote that could not be signed, was rejected by the server
+or has an invalid receipt is listed as failed. The command fails with the
+vote status if voting on the proposal is not active.
 
 Arguments:
-1. token       (string, optional)   Proposal censorship token
-2. voteid      (string, optional)   A single word identifying vote (e.g. yes)
+1. token       (string, required)   Proposal censorship token
+2. voteid      (string, required)   A single word identifying vote (e.g. yes).
+                                    Not case sensitive.
 
 Result:
 Enter the private passphrase of your wallet:
+Eligible tickets: (int)  Number of eligible tickets in the wallet
 Votes succeeded:  (int)  Number of successful votes
-Votes failed   :  (int)  Number of failed votes`
+Votes failed   :  (int)  Number of failed votes
+Failed vote    :  (string) (string)  Ticket hash and error of a failed vote`
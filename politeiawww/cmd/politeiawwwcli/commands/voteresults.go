@@ -4,73 +4,221 @@
 
 package commands
 
-// VoteResultsCmd gets the votes that have been cast for the specified
-// proposal.
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/decred/politeia/politeiawww/api/www/v1"
+)
+
+const (
+	// mainNetBlockTime and testNetBlockTime are the target times between
+	// blocks.  They are used to estimate the time remaining in a vote.
+	mainNetBlockTime = 5 * time.Minute
+	testNetBlockTime = 2 * time.Minute
+
+	// defaultVoteResultsInterval is the default time between vote status
+	// requests when watching a vote.
+	defaultVoteResultsInterval = time.Minute
+)
+
+// VoteResultsCmd gets the vote results of the specified proposal and prints
+// the vote tally, the quorum status, whether the proposal passes and the time
+// remaining in the vote.
 type VoteResultsCmd struct {
 	Args struct {
 		Token string `positional-arg-name:"token"` // Censorship token
 	} `positional-args:"true" required:"true"`
+	Watch    bool          `long:"watch" optional:"true"`    // Refresh the results until the vote ends
+	Interval time.Duration `long:"interval" optional:"true"` // Time between refreshes
 }
 
-// Execute executes the proposal votes command.
+// Execute executes the vote results command.
 func (cmd *VoteResultsCmd) Execute(args []string) error {
-	vrr, err := client.VoteResults(cmd.Args.Token)
+	interval := cmd.Interval
+	if interval == 0 {
+		interval = defaultVoteResultsInterval
+	}
+	if interval < 0 {
+		return validationError(fmt.Errorf("--interval must not be " +
+			"negative"))
+	}
+
+	// The block time of the server network is used to estimate the
+	// time remaining in the vote.
+	vr, err := client.Version()
 	if err != nil {
 		return err
 	}
+	blockTime := mainNetBlockTime
+	if vr.TestNet {
+		blockTime = testNetBlockTime
+	}
 
-	// Remove eligible tickets snapshot from response
-	// so that the output is legible
-	if !cfg.RawJSON {
-		vrr.StartVoteReply.EligibleTickets = []string{
-			"removed by politeiawwwcli for readability",
+	for {
+		// Get vote status for proposal
+		vsr, err := client.VoteStatus(cmd.Args.Token)
+		if err != nil {
+			return fmt.Errorf("VoteStatus: %v", err)
+		}
+
+		switch vsr.Status {
+		case v1.PropVoteStatusStarted, v1.PropVoteStatusFinished:
+		default:
+			return fmt.Errorf("no vote for proposal %v: %v", cmd.Args.Token,
+				v1.PropVoteStatus[vsr.Status])
+		}
+
+		if cfg.RawJSON {
+			err = printVoteResultsJSON(cmd.Args.Token)
+		} else {
+			err = printVoteResults(vsr, blockTime)
+		}
+		if err != nil {
+			return err
+		}
+
+		if !cmd.Watch || vsr.Status != v1.PropVoteStatusStarted {
+			return nil
+		}
+		time.Sleep(interval)
+		if !cfg.RawJSON {
+			fmt.Printf("\n")
 		}
 	}
+}
 
+// printVoteResultsJSON fetches the vote results of the passed in proposal and
+// prints them as JSON.
+func printVoteResultsJSON(token string) error {
+	vrr, err := client.VoteResults(token)
+	if err != nil {
+		return err
+	}
 	return printJSON(vrr)
 }
 
+// printVoteResults prints the vote tally of the passed in vote status
+// followed by the quorum and pass requirements, the result of the vote and,
+// for an active vote, an estimate of the time remaining.
+func printVoteResults(vsr *v1.VoteStatusReply, blockTime time.Duration) error {
+	total := vsr.TotalVotes
+	percent := func(votes, of uint64) float64 {
+		if of == 0 {
+			return 0
+		}
+		return float64(votes) / float64(of) * 100
+	}
+
+	// Print results
+	var yes uint64
+	for _, v := range vsr.OptionsResult {
+		if v.Option.Id == "yes" {
+			yes = v.VotesReceived
+		}
+		fmt.Printf("Vote Option:\n")
+		fmt.Printf("  ID                   : %v\n", v.Option.Id)
+		fmt.Printf("  Description          : %v\n", v.Option.Description)
+		fmt.Printf("  Bits                 : %v\n", v.Option.Bits)
+		fmt.Printf("  Votes received       : %v\n", v.VotesReceived)
+		fmt.Printf("  Percentage           : %.2f%%\n",
+			percent(v.VotesReceived, total))
+	}
+
+	// Print quorum status.  The quorum is a percentage of the eligible
+	// tickets that must vote.
+	eligible := uint64(vsr.NumOfEligibleVotes)
+	quorum := eligible * uint64(vsr.QuorumPercentage) / 100
+	quorumReached := total >= quorum
+	quorumStatus := "not reached"
+	if quorumReached {
+		quorumStatus = "reached"
+	}
+	fmt.Printf("Status                 : %v\n", v1.PropVoteStatus[vsr.Status])
+	fmt.Printf("Total votes            : %v of %v eligible (%.2f%%)\n",
+		total, eligible, percent(total, eligible))
+	fmt.Printf("Quorum                 : %v votes (%v%%), %v\n", quorum,
+		vsr.QuorumPercentage, quorumStatus)
+	fmt.Printf("Pass percentage        : %v%%\n", vsr.PassPercentage)
+
+	// Print the result.  A proposal passes when the quorum is reached
+	// and the yes votes make up at least the pass percentage of the
+	// votes cast.  The result of an active vote may still change.
+	passes := quorumReached && total > 0 &&
+		yes*100 >= total*uint64(vsr.PassPercentage)
+	result := "fail"
+	if passes {
+		result = "pass"
+	}
+	if vsr.Status == v1.PropVoteStatusStarted {
+		result += " (vote in progress)"
+	}
+	fmt.Printf("Result                 : %v\n", result)
+
+	// Print the time remaining.  Older servers do not report the best
+	// block so the time remaining can't be estimated.
+	if vsr.Status != v1.PropVoteStatusStarted || vsr.BestBlock == "" {
+		fmt.Printf("End height             : %v\n", vsr.EndHeight)
+		return nil
+	}
+	end, err := strconv.ParseUint(vsr.EndHeight, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid end height %v: %v", vsr.EndHeight, err)
+	}
+	best, err := strconv.ParseUint(vsr.BestBlock, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid best block %v: %v", vsr.BestBlock, err)
+	}
+	var remaining uint64
+	if end > best {
+		remaining = end - best
+	}
+	fmt.Printf("End height             : %v (%v blocks, ~%v remaining)\n",
+		vsr.EndHeight, remaining, time.Duration(remaining)*blockTime)
+
+	return nil
+}
+
 // voteResultsHelpMsg is the output of the help command when 'voteresults' is
 // specified.
-const voteResultsHelpMsg = `voteresults "token"
+const voteResultsHelpMsg = `voteresults [flags] "token"
 
-Fetch vote results for a proposal.
+Fetch the vote results for a proposal. The votes received by each option are
+printed along with the turnout, whether the quorum has been reached, the pass
+percentage and whether the proposal passes. The time remaining in an active
+vote is estimated from the number of blocks until the vote ends and the block
+time of the server network (5m on mainnet, 2m on testnet). With --json the
+vote results, including the cast votes, are printed as JSON.
 
 Arguments:
 1. token       (string, required)  Proposal censorship token
 
-Request:
-{
-  "token":     (string)  Proposal censorship token
-}
+Flags:
+  --watch      (bool, optional)      Refresh the results until the vote ends
+  --interval   (duration, optional)  Time between refreshes when watching a
+                                     vote (e.g. 30s). Defaults to 1m.
 
 Response:
-{
-  "startvote": {
-    "publickey"            (string)  Public key of user that submitted proposal
-    "vote": {
-      "token":             (string)  Censorship token
-      "mask"               (uint64)  Valid votebits
-      "duration":          (uint32)  Duration of vote in blocks
-      "quorumpercentage"   (uint32)  Percent of votes required for quorum
-      "passpercentage":    (uint32)  Percent of votes required to pass
-      "options": [
-        {
-          "id"             (string)  Unique word identifying vote (e.g. yes)
-          "description"    (string)  Longer description of the vote
-          "bits":          (uint64)  Bits used for this option
-        },
-      ]
-    },
-    "signature"            (string)  Signature of Votehash
-  },
-  "castvotes": [],
-  "startvotereply": {
-    "startblockheight":    (string)  Block height at start of vote
-    "startblockhash":      (string)  Hash of first block of vote interval
-    "endheight":           (string)  Block height at end of vote
-    "eligibletickets": [
-      "removed by politeiawwwcli for readability"
-    ]
-  }
-}`
+
+Vote Option:
+  ID                   : (string)  Unique word identifying vote (e.g. 'no')
+  Description          : (string)  Longer description of the vote
+  Bits                 : (uint64)  Bits used for this option (e.g. '1')
+  Votes received       : (uint64)  Number of votes received
+  Percentage           : (float64) Percentage of votes for vote option
+Vote Option:
+  ID                   : (string)  Unique word identifying vote (e.g. 'yes')
+  Description          : (string)  Longer description of the vote
+  Bits                 : (uint64)  Bits used for this option (e.g. '2')
+  Votes received       : (uint64)  Number of votes received
+  Percentage           : (float64) Percentage of votes for vote option
+Status                 : (string)  Vote status
+Total votes            : (uint64)  Votes cast, eligible tickets and turnout
+Quorum                 : (uint64)  Votes required for a quorum and whether
+                                   it has been reached
+Pass percentage        : (uint32)  Percent of votes required to pass
+Result                 : (string)  pass or fail. Marked as in progress for
+                                   an active vote
+End height             : (string)  Vote end height and, for an active vote,
+                                   the estimated time remaining`
@@ -15,9 +15,11 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"time"
 
 	"github.com/decred/dcrd/dcrutil"
 	"github.com/decred/politeia/politeiad/api/v1/identity"
+	"github.com/decred/politeia/politeiawww/api/www/v1"
 	"github.com/decred/politeia/politeiawww/sharedconfig"
 	"github.com/decred/politeia/util/version"
 	flags "github.com/jessevdk/go-flags"
@@ -31,11 +33,13 @@ const (
 	defaultFaucetHost        = "https://faucet.decred.org/requestfaucet"
 	defaultWalletHost        = "127.0.0.1"
 	defaultWalletTestnetPort = "19111"
+	defaultTimeout           = time.Minute
 
 	userFile     = "user.txt"
 	csrfFile     = "csrf.txt"
 	cookieFile   = "cookies.json"
 	identityFile = "identity.json"
+	versionFile  = "version.json"
 )
 
 var (
@@ -54,6 +58,10 @@ type Config struct {
 	SkipVerify  bool   `long:"skipverify" description:"Skip verifying the server's certifcate chain and host name"`
 	Verbose     bool   `short:"v" long:"verbose" description:"Print verbose output"`
 	Silent      bool   `long:"silent" description:"Suppress all output"`
+	Compact     bool   `long:"compact" description:"Print JSON output on a single line"`
+	Pretty      bool   `long:"pretty" description:"Print indented JSON output, also when --json is used"`
+
+	Timeout time.Duration `long:"timeout" description:"Maximum duration of each politeiawww request, e.g. 30s or 2m; 0 disables the timeout"`
 
 	DataDir    string // Application data dir
 	Version    string // CLI version
@@ -88,6 +96,7 @@ func Load() (*Config, error) {
 		WalletCert: defaultWalletCertFile,
 		FaucetHost: defaultFaucetHost,
 		Version:    version.String(),
+		Timeout:    defaultTimeout,
 	}
 
 	// Pre-parse the command line options to see if an alternative config
@@ -160,6 +169,15 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("host scheme must be http or https")
 	}
 
+	if cfg.Timeout < 0 {
+		return nil, fmt.Errorf("the 'timeout' flag must not be negative")
+	}
+
+	if cfg.Compact && cfg.Pretty {
+		return nil, fmt.Errorf("the 'compact' and 'pretty' flags cannot " +
+			"be used at the same time")
+	}
+
 	// Load cookies
 	cookies, err := cfg.loadCookies()
 	if err != nil {
@@ -283,6 +301,71 @@ func (cfg *Config) SaveCSRF(csrf string) error {
 	return nil
 }
 
+// LoadVersion returns the host specific cached version reply.  Nil is
+// returned if the version reply has not been cached or if it was cached more
+// than maxAge ago.
+func (cfg *Config) LoadVersion(maxAge time.Duration) (*v1.VersionReply, error) {
+	f, err := cfg.hostFilePath(versionFile)
+	if err != nil {
+		return nil, fmt.Errorf("hostFilePath: %v", err)
+	}
+
+	fi, err := os.Stat(f)
+	if err != nil || time.Since(fi.ModTime()) > maxAge {
+		// Nothing to load
+		return nil, nil
+	}
+
+	b, err := ioutil.ReadFile(f)
+	if err != nil {
+		return nil, fmt.Errorf("read file %v: %v", f, err)
+	}
+
+	var vr v1.VersionReply
+	err = json.Unmarshal(b, &vr)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal version: %v", err)
+	}
+
+	return &vr, nil
+}
+
+// SaveVersion writes the passed in version reply to the host specific
+// version file so that the server public key can be reused between commands.
+func (cfg *Config) SaveVersion(vr *v1.VersionReply) error {
+	b, err := json.Marshal(vr)
+	if err != nil {
+		return fmt.Errorf("marshal version: %v", err)
+	}
+
+	f, err := cfg.hostFilePath(versionFile)
+	if err != nil {
+		return fmt.Errorf("hostFilePath: %v", err)
+	}
+
+	err = ioutil.WriteFile(f, b, 0600)
+	if err != nil {
+		return fmt.Errorf("write file %v: %v", f, err)
+	}
+
+	return nil
+}
+
+// RemoveVersion deletes the host specific version file.
+func (cfg *Config) RemoveVersion() error {
+	f, err := cfg.hostFilePath(versionFile)
+	if err != nil {
+		return fmt.Errorf("hostFilePath: %v", err)
+	}
+
+	err = os.Remove(f)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("remove file %v: %v", f, err)
+	}
+
+	return nil
+}
+
 // identityFilePath returns the file path for a specific user identity.  We
 // store identities in a user specific file so that we can keep track of the
 // identities of multiple users.
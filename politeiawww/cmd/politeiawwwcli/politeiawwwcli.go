@@ -58,7 +58,7 @@ func _main() error {
 		if ok && flagsErr.Type == flags.ErrHelp {
 			os.Exit(0)
 		} else {
-			os.Exit(1)
+			os.Exit(commands.ExitCode(err))
 		}
 	}
 
@@ -0,0 +1,856 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package cmsutil provides the invoice helpers that are shared by politeiawww
+// and politeiawwwcli, such as the invoice csv parser, so that other tools can
+// validate and import invoices the same way.
+package cmsutil
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/decred/politeia/politeiawww/api/cms/v1"
+	www "github.com/decred/politeia/politeiawww/api/www/v1"
+	"github.com/decred/politeia/util"
+)
+
+// Options contains the options that can be used to customize how an invoice
+// csv is parsed.  The zero value uses the invoice policy defaults.
+type Options struct {
+	Delimiter   rune // Line item field delimiter
+	CommentChar rune // Comment line prefix
+	Rate        uint // Expected labor rate in atoms per hour
+	SkipHeader  bool // Ignore the first record (column names)
+
+	// SkipTokenCheck skips validating that proposal tokens are
+	// formatted as censorship tokens.
+	SkipTokenCheck bool
+
+	// Month and Year are the invoice month and year.  Line item dates
+	// must fall within the invoice month when they are set.
+	Month int
+	Year  int
+
+	// Precision is the number of decimal places that hours and costs
+	// are rounded to.  DefaultPrecision is used when it is nil.
+	Precision *uint
+
+	// LazyQuotes allows quotes to appear in unquoted fields and
+	// unescaped quotes to appear in quoted fields.
+	LazyQuotes bool
+
+	// MaxLineItems is the maximum number of line items.
+	// DefaultMaxLineItems is used when it is 0.
+	MaxLineItems uint
+
+	// Subtypes contains the allowed subtypes per line item type.
+	// Subtypes are not validated when it is nil, nor for types that
+	// have no entry.
+	Subtypes map[v1.LineItemTypeT][]string
+
+	// FieldMapping contains the 0-based csv column of each line item
+	// field, indexed in the order of LineItemFields, or -1 for an
+	// optional field that is not in the csv.  Columns that are not
+	// mapped are ignored.  The default column order is used when it
+	// is nil.  See ParseFieldMapping.
+	FieldMapping []int
+}
+
+// LineItemFields contains the names of the line item fields in the default
+// csv column order.  The first www.PolicyInvoiceLineItemCount fields are
+// required.
+var LineItemFields = []string{
+	"type",
+	"subtype",
+	"description",
+	"token",
+	"hours",
+	"cost",
+	"currency",
+	"startdate",
+	"enddate",
+	"tags",
+}
+
+// ParseFieldMapping parses a field mapping of the form
+// type=0,subtype=2,description=1,token=3,hours=5,cost=4 into the column
+// indexes used by Options.FieldMapping.  All required fields must be mapped
+// and no two fields may be mapped to the same column.
+func ParseFieldMapping(s string) ([]int, error) {
+	mapping := make([]int, len(LineItemFields))
+	for i := range mapping {
+		mapping[i] = -1
+	}
+	columns := make(map[int]string, len(LineItemFields))
+	for _, v := range strings.Split(s, ",") {
+		kv := strings.SplitN(v, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid field mapping %q: expected "+
+				"field=column", v)
+		}
+		name := strings.ToLower(strings.TrimSpace(kv[0]))
+		field := -1
+		for i, f := range LineItemFields {
+			if name == f {
+				field = i
+				break
+			}
+		}
+		if field == -1 {
+			return nil, fmt.Errorf("invalid field mapping %q: unknown "+
+				"field %q; the fields are %v", v, name,
+				strings.Join(LineItemFields, ", "))
+		}
+		if mapping[field] != -1 {
+			return nil, fmt.Errorf("invalid field mapping: field %v is "+
+				"mapped more than once", name)
+		}
+		column, err := strconv.ParseUint(strings.TrimSpace(kv[1]), 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("invalid field mapping %q: column "+
+				"must be a non-negative integer", v)
+		}
+		if other, ok := columns[int(column)]; ok {
+			return nil, fmt.Errorf("invalid field mapping: fields %v "+
+				"and %v are both mapped to column %v", other, name, column)
+		}
+		columns[int(column)] = name
+		mapping[field] = int(column)
+	}
+	for i := 0; i < www.PolicyInvoiceLineItemCount; i++ {
+		if mapping[i] == -1 {
+			return nil, fmt.Errorf("invalid field mapping: field %v is "+
+				"not mapped", LineItemFields[i])
+		}
+	}
+	return mapping, nil
+}
+
+// mapFields reorders the passed in csv record into the default column order
+// using the passed in field mapping.  Optional fields that are not mapped are
+// left empty.  A LineItemError is returned if a mapped column is not in the
+// record.
+func mapFields(line int, record []string, mapping []int) ([]string, error) {
+	n := www.PolicyInvoiceLineItemCount
+	for i, column := range mapping {
+		if column >= len(record) {
+			return nil, malformedLineError(line, 0, ErrLineItemFieldCount,
+				"field %v (%v) is mapped to column %v but the line only "+
+					"has %v columns", i+1, LineItemFields[i], column,
+				len(record))
+		}
+		if column != -1 && i >= n {
+			n = i + 1
+		}
+	}
+	fields := make([]string, n)
+	for i := range fields {
+		if i < len(mapping) && mapping[i] != -1 {
+			fields[i] = record[mapping[i]]
+		}
+	}
+	return fields, nil
+}
+
+// mappedFieldError updates the field number of the passed in LineItemError
+// to the csv column that the field was mapped from.
+func mappedFieldError(err error, mapping []int) error {
+	le, ok := err.(*LineItemError)
+	if !ok || le.Field == 0 || mapping == nil {
+		return err
+	}
+	le.Field = mapping[le.Field-1] + 1
+	le.Detail += fmt.Sprintf(" (column %v)", le.Field-1)
+	return le
+}
+
+// utf8BOM is the UTF-8 encoded byte order mark.
+var utf8BOM = []byte{0xef, 0xbb, 0xbf}
+
+// NormalizeCSV strips the UTF-8 byte order mark that some spreadsheet
+// programs prepend to exported csv files and converts CRLF and CR line
+// endings to LF so that no carriage returns end up in the line item fields.
+func NormalizeCSV(data []byte) []byte {
+	data = bytes.TrimPrefix(data, utf8BOM)
+	data = bytes.Replace(data, []byte("\r\n"), []byte("\n"), -1)
+	return bytes.Replace(data, []byte("\r"), []byte("\n"), -1)
+}
+
+// DefaultMaxLineItems is the default maximum number of line items that an
+// invoice csv may contain.
+const DefaultMaxLineItems = 500
+
+// LineItemCountError is returned when an invoice contains more line items
+// than allowed.
+type LineItemCountError struct {
+	Count int  // Number of line items
+	Max   uint // Maximum number of line items
+}
+
+// Error satisfies the error interface.
+func (e *LineItemCountError) Error() string {
+	return fmt.Sprintf("invoice contains %v line items, the maximum is %v",
+		e.Count, e.Max)
+}
+
+// CheckLineItemCount returns a LineItemCountError if the number of line
+// items exceeds the passed in maximum.  DefaultMaxLineItems is used when max
+// is 0.
+func CheckLineItemCount(n int, max uint) error {
+	if max == 0 {
+		max = DefaultMaxLineItems
+	}
+	if n > int(max) {
+		return &LineItemCountError{
+			Count: n,
+			Max:   max,
+		}
+	}
+	return nil
+}
+
+// DefaultPrecision is the default number of decimal places that line item
+// hours and costs are rounded to.
+const DefaultPrecision = 2
+
+// roundToPrecision rounds the passed in value to the specified number of
+// decimal places.  This removes the floating point noise of spreadsheet
+// exports, e.g. 1.2500001, which would otherwise change the merkle root.
+func roundToPrecision(v float64, precision uint) float64 {
+	p := math.Pow(10, float64(precision))
+	return math.Round(v*p) / p
+}
+
+// lineItemDateLayouts contains the accepted line item date formats.
+var lineItemDateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02",
+}
+
+// parseLineItemDate parses a line item start or end date.  Dates without a
+// time zone are treated as UTC.
+func parseLineItemDate(date string) (time.Time, error) {
+	var (
+		t   time.Time
+		err error
+	)
+	for _, layout := range lineItemDateLayouts {
+		t, err = time.Parse(layout, date)
+		if err == nil {
+			return t, nil
+		}
+	}
+	return t, err
+}
+
+// currencyRegexp matches the three letter currency codes accepted in the
+// optional currency field of expense, misc and credit line items, e.g.
+// USD.
+var currencyRegexp = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// rateTolerance is the maximum amount that the cost of a labor line item may
+// differ from hours * rate to allow for rounding.
+const rateTolerance = 1.0
+
+var (
+	// ErrLineItemFieldCount is emitted when a csv line does not
+	// contain the number of fields required by the invoice policy.
+	ErrLineItemFieldCount = errors.New("invalid number of fields")
+
+	// ErrUnknownLineItemType is emitted when the line item type is
+	// not labor, expense, misc or credit.
+	ErrUnknownLineItemType = errors.New("unknown line item type")
+
+	// ErrLineItemBadFloat is emitted when the line item hours or
+	// cost cannot be parsed as a float.
+	ErrLineItemBadFloat = errors.New("invalid float")
+
+	// ErrLineItemCostMismatch is emitted when the cost of a labor
+	// line item does not match the hours times the expected rate.
+	ErrLineItemCostMismatch = errors.New("cost does not match rate")
+
+	// ErrLineItemFieldLength is emitted when a line item field
+	// exceeds its maximum length.
+	ErrLineItemFieldLength = errors.New("field too long")
+
+	// ErrLineItemBadToken is emitted when the line item proposal
+	// token is not a valid censorship token.
+	ErrLineItemBadToken = errors.New("invalid proposal token")
+
+	// ErrLineItemBadCurrency is emitted when the line item currency
+	// is not a three letter currency code or is used on a labor line
+	// item.
+	ErrLineItemBadCurrency = errors.New("invalid currency")
+
+	// ErrLineItemBadDate is emitted when a line item start or end
+	// date cannot be parsed or is outside of the invoice month.
+	ErrLineItemBadDate = errors.New("invalid date")
+
+	// ErrLineItemBadAmount is emitted when the line item hours or
+	// cost is negative, not a finite number or out of range.
+	ErrLineItemBadAmount = errors.New("invalid amount")
+
+	// ErrLineItemBadSubtype is emitted when the line item subtype is
+	// not one of the subtypes that the server allows.
+	ErrLineItemBadSubtype = errors.New("invalid subtype")
+
+	// ErrNoLineItems is returned when the csv does not contain any
+	// line items, e.g. when it is empty or only contains comments.
+	ErrNoLineItems = errors.New("invoice has no line items")
+)
+
+// LineItemError is returned by ParseInvoiceCSV when a csv line is malformed.
+// Err is one of the ErrLineItem sentinel errors and can be inspected using
+// Unwrap.  Line is the line of the file that the csv record starts on, so
+// comment and blank lines are counted, as are the lines of a quoted field
+// that spans multiple lines.
+type LineItemError struct {
+	Line   int    // 1-based csv line number
+	Field  int    // 1-based field number, 0 if not field specific
+	Err    error  // Sentinel error
+	Detail string // Human readable description
+}
+
+// Error satisfies the error interface.
+func (e *LineItemError) Error() string {
+	return fmt.Sprintf("line %v: %v", e.Line, e.Detail)
+}
+
+// Unwrap returns the sentinel error.
+func (e *LineItemError) Unwrap() error {
+	return e.Err
+}
+
+// UserError returns the malformed invoice file UserError that the server
+// returns for the same csv line.
+func (e *LineItemError) UserError() www.UserError {
+	return www.UserError{
+		ErrorCode:    www.ErrorStatusMalformedInvoiceFile,
+		ErrorContext: []string{e.Error()},
+	}
+}
+
+// malformedLineError returns a LineItemError that describes the offending csv
+// line.  The line and field numbers are 1-based.
+func malformedLineError(line, field int, sentinel error, format string, args ...interface{}) error {
+	return &LineItemError{
+		Line:   line,
+		Field:  field,
+		Err:    sentinel,
+		Detail: fmt.Sprintf(format, args...),
+	}
+}
+
+// parseLineItemAmount parses the hours or cost field of a csv line.  Amounts
+// must be finite and must not be negative unless allowNegative is set.
+func parseLineItemAmount(line, field int, name, s string, allowNegative bool) (float64, error) {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		if ne, ok := err.(*strconv.NumError); ok && ne.Err == strconv.ErrRange {
+			return 0, malformedLineError(line, field, ErrLineItemBadAmount,
+				"field %v (%v) is out of range: got '%v'", field, name, s)
+		}
+		return 0, malformedLineError(line, field, ErrLineItemBadFloat,
+			"field %v (%v) not a valid float: got '%v'", field, name, s)
+	}
+	switch {
+	case math.IsNaN(f), math.IsInf(f, 0):
+		return 0, malformedLineError(line, field, ErrLineItemBadAmount,
+			"field %v (%v) must be a finite number: got '%v'", field, name,
+			s)
+	case f < 0 && !allowNegative:
+		return 0, malformedLineError(line, field, ErrLineItemBadAmount,
+			"field %v (%v) must not be negative: got '%v'", field, name, s)
+	}
+	return f, nil
+}
+
+// parseLineItemHours parses the hours field of a csv line.  Hours are either
+// a decimal number or a duration in the HH:MM format, e.g. 37:30 for 37.5
+// hours, as exported by time tracking tools.
+func parseLineItemHours(line, field int, s string) (float64, error) {
+	i := strings.Index(s, ":")
+	if i == -1 {
+		return parseLineItemAmount(line, field, "hours", s, false)
+	}
+	h, m := s[:i], s[i+1:]
+	hours, herr := strconv.ParseUint(h, 10, 32)
+	minutes, merr := strconv.ParseUint(m, 10, 8)
+	if herr != nil || merr != nil || len(m) != 2 || minutes > 59 {
+		return 0, malformedLineError(line, field, ErrLineItemBadAmount,
+			"field %v (hours) not a valid number or HH:MM duration: "+
+				"got '%v'", field, s)
+	}
+	return float64(hours) + float64(minutes)/60, nil
+}
+
+// parseLineItemTags splits the tags field of a csv line into its tags.  Tags
+// are separated by commas or semicolons; empty tags are dropped.
+func parseLineItemTags(line, field int, s string) ([]string, error) {
+	var tags []string
+	for _, v := range strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ';'
+	}) {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		if utf8.RuneCountInString(v) > www.PolicyInvoiceMaxSubtypeLength {
+			return nil, malformedLineError(line, field,
+				ErrLineItemFieldLength,
+				"field %v (tags) tag %q exceeds the maximum length of %v",
+				field, v, www.PolicyInvoiceMaxSubtypeLength)
+		}
+		tags = append(tags, v)
+	}
+	return tags, nil
+}
+
+// stringInSlice returns whether the passed in string is in the slice.
+func stringInSlice(s []string, str string) bool {
+	for _, v := range s {
+		if v == str {
+			return true
+		}
+	}
+	return false
+}
+
+// subtypeSuggestion returns a suggestion of the allowed subtype that is the
+// closest match to the passed in subtype.  An empty string is returned if
+// no allowed subtype is close.
+func subtypeSuggestion(allowed []string, subtype string) string {
+	lower := strings.ToLower(subtype)
+
+	// Abbreviations such as dev for development are suggested first
+	if lower != "" {
+		for _, v := range allowed {
+			if strings.HasPrefix(strings.ToLower(v), lower) {
+				return fmt.Sprintf("; did you mean %q?", v)
+			}
+		}
+	}
+
+	// Otherwise suggest the closest subtype if less than half of it
+	// differs.
+	var (
+		best     string
+		bestDist = -1
+	)
+	for _, v := range allowed {
+		d := levenshtein(strings.ToLower(v), lower)
+		if bestDist == -1 || d < bestDist {
+			best, bestDist = v, d
+		}
+	}
+	if best == "" || bestDist*2 >= utf8.RuneCountInString(best) {
+		return ""
+	}
+	return fmt.Sprintf("; did you mean %q?", best)
+}
+
+// levenshtein returns the edit distance between the passed in strings.
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+// min3 returns the smallest of the passed in integers.
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
+
+// recordLines returns the line that each record of the passed in normalized
+// csv starts on, as it is read by the passed in csv reader.  The csv reader
+// skips blank lines and comments and a quoted field may span multiple lines,
+// so the record numbers and the line numbers of a csv differ.
+func recordLines(data []byte, r *csv.Reader) []int {
+	var (
+		lines  []int
+		quoted bool // Within a quoted field that continues on the next line
+	)
+	for i, line := range strings.Split(string(data), "\n") {
+		if !quoted {
+			if line == "" || (r.Comment != 0 &&
+				strings.HasPrefix(line, string(r.Comment))) {
+				continue
+			}
+			lines = append(lines, i+1)
+		}
+		quoted = continuesQuoted(line, quoted, r)
+	}
+	return lines
+}
+
+// continuesQuoted returns whether the passed in csv line ends within a quoted
+// field.  Quoted specifies whether the line starts within a quoted field.
+func continuesQuoted(line string, quoted bool, r *csv.Reader) bool {
+	comma := string(r.Comma)
+	for {
+		if !quoted {
+			if r.TrimLeadingSpace {
+				line = strings.TrimLeftFunc(line, unicode.IsSpace)
+			}
+			if !strings.HasPrefix(line, `"`) {
+				// Quotes are literal within an unquoted field
+				i := strings.Index(line, comma)
+				if i == -1 {
+					return false
+				}
+				line = line[i+len(comma):]
+				continue
+			}
+			line = line[1:]
+		}
+
+		i := strings.IndexByte(line, '"')
+		if i == -1 {
+			return true
+		}
+		line = line[i+1:]
+		switch {
+		case strings.HasPrefix(line, `"`):
+			// Escaped quote
+			line = line[1:]
+			quoted = true
+		case strings.HasPrefix(line, comma):
+			line = line[len(comma):]
+			quoted = false
+		case line == "":
+			return false
+		default:
+			// Stray quote, only accepted with lazy quotes
+			quoted = true
+		}
+	}
+}
+
+// ParseInvoiceCSVLines parses the passed in invoice csv like ParseInvoiceCSV
+// and also returns the csv line that each of the line items was read from.
+func ParseInvoiceCSVLines(data []byte, opts Options) (*v1.InvoiceInput, []int, error) {
+	invInput, lines, err := parseInvoiceCSV(data, opts)
+	if err != nil {
+		err = mappedFieldError(err, opts.FieldMapping)
+	}
+	return invInput, lines, err
+}
+
+// ParseInvoiceCSV validates and parses the passed in invoice csv into an
+// invoice input.  A LineItemError is returned for a malformed line item, a
+// LineItemCountError when the csv contains too many line items, ErrNoLineItems
+// when it contains none and a csv.ParseError when the csv itself is
+// malformed.  The field number of a LineItemError is the csv column that the
+// field was read from when a field mapping is used.  The returned invoice
+// input is never nil and records the csv format that was used.
+func ParseInvoiceCSV(data []byte, opts Options) (*v1.InvoiceInput, error) {
+	invInput, _, err := ParseInvoiceCSVLines(data, opts)
+	return invInput, err
+}
+
+// parseInvoiceCSV parses the passed in invoice csv and returns the csv line
+// of each line item.  The field numbers of the returned errors refer to the
+// default column order.
+func parseInvoiceCSV(data []byte, opts Options) (*v1.InvoiceInput, []int, error) {
+	LineItemType := map[string]v1.LineItemTypeT{
+		"labor":   v1.LineItemTypeLabor,
+		"expense": v1.LineItemTypeExpense,
+		"misc":    v1.LineItemTypeMisc,
+		"credit":  v1.LineItemTypeCredit,
+	}
+	invInput := &v1.InvoiceInput{}
+
+	data = NormalizeCSV(data)
+
+	// Validate that the invoice is CSV-formatted.
+	csvReader := csv.NewReader(strings.NewReader(string(data)))
+	csvReader.Comma = www.PolicyInvoiceFieldDelimiterChar
+	if opts.Delimiter != 0 {
+		csvReader.Comma = opts.Delimiter
+	}
+	csvReader.Comment = www.PolicyInvoiceCommentChar
+	if opts.CommentChar != 0 {
+		csvReader.Comment = opts.CommentChar
+	}
+	// Leading white space is trimmed from each field, unless the
+	// delimiter is white space itself, e.g. a tab, since empty fields
+	// would be collapsed otherwise.  The fields are trimmed below.
+	csvReader.TrimLeadingSpace = !unicode.IsSpace(csvReader.Comma)
+	csvReader.LazyQuotes = opts.LazyQuotes
+	// The field count of each line is validated below so that the
+	// offending line can be reported.
+	csvReader.FieldsPerRecord = -1
+
+	// Record the csv format so that the line items can be exported
+	// using the same format.
+	invInput.CSVMetadata = &v1.InvoiceCSVMetadata{
+		FieldDelimiterChar: csvReader.Comma,
+		CommentChar:        csvReader.Comment,
+	}
+
+	csvFields, err := csvReader.ReadAll()
+	if err != nil {
+		return invInput, nil, err
+	}
+
+	// Errors report the csv line that the record starts on.  The
+	// record number is used should the lines not match the records.
+	lines := recordLines(data, csvReader)
+	if len(lines) != len(csvFields) {
+		lines = make([]int, len(csvFields))
+		for i := range lines {
+			lines[i] = i + 1
+		}
+	}
+
+	// Drop the header record if specified
+	if opts.SkipHeader && len(csvFields) > 0 {
+		csvFields = csvFields[1:]
+		lines = lines[1:]
+	}
+
+	if len(csvFields) == 0 {
+		return invInput, nil, ErrNoLineItems
+	}
+	err = CheckLineItemCount(len(csvFields), opts.MaxLineItems)
+	if err != nil {
+		return invInput, nil, err
+	}
+
+	lineItems := make([]v1.LineItemsInput, 0, len(csvFields))
+	// Validate that line items are the correct length and contents in
+	// field 4 and 5 are parsable to floats.  Hours may be left empty for
+	// expense, misc and credit line items.
+	for i, lineContents := range csvFields {
+		lineItem := v1.LineItemsInput{}
+		line := lines[i]
+		if opts.FieldMapping != nil {
+			lineContents, err = mapFields(line, lineContents,
+				opts.FieldMapping)
+			if err != nil {
+				return invInput, nil, err
+			}
+		}
+		// The csv reader only trims leading whitespace
+		for j := range lineContents {
+			lineContents[j] = strings.TrimSpace(lineContents[j])
+		}
+		// The currency, start date, end date and tags fields are
+		// optional
+		if len(lineContents) < www.PolicyInvoiceLineItemCount ||
+			len(lineContents) > www.PolicyInvoiceLineItemCount+4 {
+			hint := "is a field missing?"
+			if len(lineContents) > www.PolicyInvoiceLineItemCount {
+				hint = "did an unquoted " + string(csvReader.Comma) +
+					" sneak into a description?"
+			}
+			return invInput, nil, malformedLineError(line, 0,
+				ErrLineItemFieldCount,
+				"expected %v fields (up to %v with the optional fields), "+
+					"got %v; %v The line was: %v", www.PolicyInvoiceLineItemCount,
+				www.PolicyInvoiceLineItemCount+4, len(lineContents), hint,
+				strings.Join(lineContents, string(csvReader.Comma)))
+		}
+		lineItemType, ok := LineItemType[strings.ToLower(lineContents[0])]
+		if !ok {
+			return invInput, nil, malformedLineError(line, 1,
+				ErrUnknownLineItemType,
+				"field 1 (type) not a valid line item type: got '%v'",
+				lineContents[0])
+		}
+		// Hours are only required for labor line items
+		var hours float64
+		if lineContents[4] != "" || lineItemType == v1.LineItemTypeLabor {
+			hours, err = parseLineItemHours(line, 5, lineContents[4])
+			if err != nil {
+				return invInput, nil, err
+			}
+		}
+		// Credits offset the invoice total so their cost is negative
+		credit := lineItemType == v1.LineItemTypeCredit
+		cost, err := parseLineItemAmount(line, 6, "cost", lineContents[5],
+			credit)
+		if err != nil {
+			return invInput, nil, err
+		}
+		if credit && hours != 0 {
+			return invInput, nil, malformedLineError(line, 5,
+				ErrLineItemBadAmount,
+				"field 5 (hours) must be empty or zero for credit line "+
+					"items: got '%v'", lineContents[4])
+		}
+		if credit && cost > 0 {
+			return invInput, nil, malformedLineError(line, 6,
+				ErrLineItemBadAmount,
+				"field 6 (cost) must be negative or zero for credit line "+
+					"items: got '%v'", lineContents[5])
+		}
+		precision := uint(DefaultPrecision)
+		if opts.Precision != nil {
+			precision = *opts.Precision
+		}
+		hours = roundToPrecision(hours, precision)
+		cost = roundToPrecision(cost, precision)
+		lineItem.LineNumber = uint16(i)
+
+		if opts.Rate != 0 && lineItemType == v1.LineItemTypeLabor {
+			expected := hours * float64(opts.Rate)
+			if math.Abs(cost-expected) > rateTolerance {
+				return invInput, nil, malformedLineError(line, 6,
+					ErrLineItemCostMismatch,
+					"field 6 (cost) does not match hours * rate: "+
+						"got %v, expected %v", cost, expected)
+			}
+		}
+		if utf8.RuneCountInString(lineContents[1]) >
+			www.PolicyInvoiceMaxSubtypeLength {
+			return invInput, nil, malformedLineError(line, 2,
+				ErrLineItemFieldLength,
+				"field 2 (subtype) exceeds the maximum length of %v",
+				www.PolicyInvoiceMaxSubtypeLength)
+		}
+		if allowed, ok := opts.Subtypes[lineItemType]; ok &&
+			!stringInSlice(allowed, lineContents[1]) {
+			return invInput, nil, malformedLineError(line, 2,
+				ErrLineItemBadSubtype,
+				"field 2 (subtype) %q is not an allowed %v subtype%v",
+				lineContents[1], lineContents[0],
+				subtypeSuggestion(allowed, lineContents[1]))
+		}
+		if utf8.RuneCountInString(lineContents[2]) >
+			www.PolicyInvoiceMaxDescriptionLength {
+			return invInput, nil, malformedLineError(line, 3,
+				ErrLineItemFieldLength,
+				"field 3 (description) exceeds the maximum length of %v",
+				www.PolicyInvoiceMaxDescriptionLength)
+		}
+		if lineContents[3] != "" && !opts.SkipTokenCheck {
+			_, err := util.ConvertStringToken(lineContents[3])
+			if err != nil {
+				return invInput, nil, malformedLineError(line, 4,
+					ErrLineItemBadToken,
+					"field 4 (token) not a valid censorship token: "+
+						"got '%v'", lineContents[3])
+			}
+		}
+		var currency string
+		if len(lineContents) > www.PolicyInvoiceLineItemCount {
+			currency = strings.ToUpper(lineContents[6])
+		}
+		if currency != "" {
+			if lineItemType == v1.LineItemTypeLabor {
+				return invInput, nil, malformedLineError(line, 7,
+					ErrLineItemBadCurrency,
+					"field 7 (currency) is only allowed for expense, "+
+						"misc and credit line items")
+			}
+			if !currencyRegexp.MatchString(currency) {
+				return invInput, nil, malformedLineError(line, 7,
+					ErrLineItemBadCurrency,
+					"field 7 (currency) not a valid currency code: got '%v'",
+					lineContents[6])
+			}
+		}
+		var startDate, endDate time.Time
+		if len(lineContents) > 7 && lineContents[7] != "" {
+			startDate, err = parseLineItemDate(lineContents[7])
+			if err != nil {
+				return invInput, nil, malformedLineError(line, 8,
+					ErrLineItemBadDate,
+					"field 8 (startdate) not a valid date: got '%v'",
+					lineContents[7])
+			}
+		}
+		if len(lineContents) > 8 && lineContents[8] != "" {
+			endDate, err = parseLineItemDate(lineContents[8])
+			if err != nil {
+				return invInput, nil, malformedLineError(line, 9,
+					ErrLineItemBadDate,
+					"field 9 (enddate) not a valid date: got '%v'",
+					lineContents[8])
+			}
+		}
+		if !startDate.IsZero() && !endDate.IsZero() &&
+			endDate.Before(startDate) {
+			return invInput, nil, malformedLineError(line, 9,
+				ErrLineItemBadDate,
+				"field 9 (enddate) is before field 8 (startdate)")
+		}
+		if opts.Month != 0 && opts.Year != 0 {
+			first := time.Date(opts.Year, time.Month(opts.Month), 1, 0, 0,
+				0, 0, time.UTC)
+			next := first.AddDate(0, 1, 0)
+			if !startDate.IsZero() &&
+				(startDate.Before(first) || !startDate.Before(next)) {
+				return invInput, nil, malformedLineError(line, 8,
+					ErrLineItemBadDate,
+					"field 8 (startdate) is not within the invoice month: "+
+						"got '%v'", lineContents[7])
+			}
+			if !endDate.IsZero() &&
+				(endDate.Before(first) || !endDate.Before(next)) {
+				return invInput, nil, malformedLineError(line, 9,
+					ErrLineItemBadDate,
+					"field 9 (enddate) is not within the invoice month: "+
+						"got '%v'", lineContents[8])
+			}
+		}
+		var tags []string
+		if len(lineContents) > 9 {
+			tags, err = parseLineItemTags(line, 10, lineContents[9])
+			if err != nil {
+				return invInput, nil, err
+			}
+		}
+		lineItem.Type = lineItemType
+		lineItem.Subtype = lineContents[1]
+		lineItem.Description = lineContents[2]
+		lineItem.ProposalToken = lineContents[3]
+		lineItem.Hours = hours
+		lineItem.TotalCost = cost
+		lineItem.Currency = currency
+		if !startDate.IsZero() {
+			lineItem.StartDate = startDate.Unix()
+		}
+		if !endDate.IsZero() {
+			lineItem.EndDate = endDate.Unix()
+		}
+		lineItem.Tags = tags
+		lineItems = append(lineItems, lineItem)
+	}
+	invInput.LineItems = lineItems
+
+	return invInput, lines, nil
+}
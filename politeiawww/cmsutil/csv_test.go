@@ -0,0 +1,416 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package cmsutil
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/decred/politeia/politeiawww/api/cms/v1"
+)
+
+// update rewrites the golden files with the current parser output.
+var update = flag.Bool("update", false, "update the golden files")
+
+func TestParseInvoiceCSVErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		csv   string
+		line  int
+		field int
+		err   error
+	}{
+		{"field count", "labor,dev,desc,,10\n", 1, 0, ErrLineItemFieldCount},
+		{"type", "labor,dev,desc,,10,400\nwork,dev,desc,,10,400\n",
+			2, 1, ErrUnknownLineItemType},
+		{"hours", "labor,dev,desc,,ten,400\n", 1, 5, ErrLineItemBadFloat},
+		{"cost", "expense,dev,desc,,,abc\n", 1, 6, ErrLineItemBadFloat},
+		{"token", "labor,dev,desc,abc,10,400\n", 1, 4, ErrLineItemBadToken},
+		{"inf hours", "labor,dev,desc,,inf,400\n", 1, 5, ErrLineItemBadAmount},
+		{"nan hours", "labor,dev,desc,,NaN,400\n", 1, 5, ErrLineItemBadAmount},
+		{"negative hours", "labor,dev,desc,,-5,400\n", 1, 5,
+			ErrLineItemBadAmount},
+		{"overflow hours", "labor,dev,desc,,1e400,400\n", 1, 5,
+			ErrLineItemBadAmount},
+		{"inf cost", "expense,dev,desc,,,-Inf\n", 1, 6, ErrLineItemBadAmount},
+		{"nan cost", "expense,dev,desc,,,nan\n", 1, 6, ErrLineItemBadAmount},
+		{"negative cost", "labor,dev,desc,,10,400\nexpense,dev,desc,,,-5\n",
+			2, 6, ErrLineItemBadAmount},
+		{"overflow cost", "expense,dev,desc,,,1e400\n", 1, 6,
+			ErrLineItemBadAmount},
+	}
+	for _, test := range tests {
+		_, err := ParseInvoiceCSV([]byte(test.csv), Options{})
+		lie, ok := err.(*LineItemError)
+		if !ok {
+			t.Errorf("%v: got error %v, want LineItemError", test.name, err)
+			continue
+		}
+		if lie.Unwrap() != test.err {
+			t.Errorf("%v: got error %v, want %v", test.name, lie.Unwrap(),
+				test.err)
+		}
+		if lie.Line != test.line || lie.Field != test.field {
+			t.Errorf("%v: got line %v field %v, want line %v field %v",
+				test.name, lie.Line, lie.Field, test.line, test.field)
+		}
+	}
+}
+
+func TestParseInvoiceCSVLineEndingsErrorLine(t *testing.T) {
+	csv := "labor,dev,desc,,10,400\r\nlabor,dev,desc,,ten,400\r\n"
+	_, err := ParseInvoiceCSV([]byte(csv), Options{})
+	lie, ok := err.(*LineItemError)
+	if !ok {
+		t.Fatalf("got error %v, want LineItemError", err)
+	}
+	if lie.Line != 2 || lie.Field != 5 {
+		t.Fatalf("got line %v field %v, want line 2 field 5", lie.Line,
+			lie.Field)
+	}
+}
+
+// readFixture returns the contents of the passed in testdata file.
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	b, err := ioutil.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// testFieldMapping is the field mapping of the field-mapping fixtures:
+// type=2,subtype=0,description=1,token=3,hours=5,cost=4.
+var testFieldMapping = []int{2, 0, 1, 3, 5, 4, -1, -1, -1, -1}
+
+func TestParseFieldMapping(t *testing.T) {
+	mapping, err := ParseFieldMapping("type=2,subtype=0,description=1," +
+		"token=3,hours=5,cost=4")
+	if err != nil {
+		t.Fatalf("ParseFieldMapping: %v", err)
+	}
+	if !reflect.DeepEqual(mapping, testFieldMapping) {
+		t.Fatalf("got mapping %v, want %v", mapping, testFieldMapping)
+	}
+
+	tests := []struct {
+		name    string
+		mapping string
+	}{
+		{"missing field", "type=0,subtype=1,description=2,token=3,hours=4"},
+		{"unknown field", "type=0,subtype=1,description=2,token=3,hours=4," +
+			"cost=5,notes=6"},
+		{"duplicate field", "type=0,subtype=1,description=2,token=3," +
+			"hours=4,cost=5,type=6"},
+		{"duplicate column", "type=0,subtype=1,description=2,token=3," +
+			"hours=4,cost=4"},
+		{"negative column", "type=-1,subtype=1,description=2,token=3," +
+			"hours=4,cost=5"},
+		{"malformed", "type:0,subtype=1,description=2,token=3,hours=4," +
+			"cost=5"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ParseFieldMapping(test.mapping)
+			if err == nil {
+				t.Fatalf("expected error for %v", test.mapping)
+			}
+		})
+	}
+}
+
+func TestParseInvoiceCSVGolden(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+	}{
+		{"basic", Options{}},
+		{"comments", Options{}},
+		{"header", Options{SkipHeader: true}},
+		{"optional-fields", Options{Month: 1, Year: 2019}},
+		{"tab-delimited", Options{Delimiter: '\t'}},
+		{"quoted", Options{}},
+		{"whitespace", Options{}},
+		{"precision", Options{}},
+		{"bom-crlf", Options{}},
+		{"credit", Options{}},
+		{"hours-duration", Options{}},
+		{"tags", Options{}},
+		{"field-mapping", Options{FieldMapping: testFieldMapping}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data := readFixture(t, filepath.Join("valid", test.name+".csv"))
+			invInput, err := ParseInvoiceCSV(data, test.opts)
+			if err != nil {
+				t.Fatalf("ParseInvoiceCSV: %v", err)
+			}
+			got, err := json.MarshalIndent(invInput, "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, '\n')
+
+			golden := filepath.Join("testdata", "valid", test.name+".golden")
+			if *update {
+				err := ioutil.WriteFile(golden, got, 0644)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := ioutil.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestParseInvoiceCSVGoldenErrors(t *testing.T) {
+	january := Options{Month: 1, Year: 2019}
+	tests := []struct {
+		name  string
+		opts  Options
+		line  int
+		field int
+		err   error
+	}{
+		{"field-count-short", Options{}, 1, 0, ErrLineItemFieldCount},
+		{"field-count-long", Options{}, 1, 0, ErrLineItemFieldCount},
+		{"unknown-type", Options{}, 2, 1, ErrUnknownLineItemType},
+		{"bad-float-hours", Options{}, 1, 5, ErrLineItemBadFloat},
+		{"bad-float-cost", Options{}, 1, 6, ErrLineItemBadFloat},
+		{"infinite-hours", Options{}, 1, 5, ErrLineItemBadAmount},
+		{"negative-cost", Options{}, 1, 6, ErrLineItemBadAmount},
+		{"overflow-cost", Options{}, 1, 6, ErrLineItemBadAmount},
+		{"cost-mismatch", Options{Rate: 40}, 1, 6, ErrLineItemCostMismatch},
+		{"subtype-length", Options{}, 1, 2, ErrLineItemFieldLength},
+		{"subtype-not-allowed", Options{
+			Subtypes: map[v1.LineItemTypeT][]string{
+				v1.LineItemTypeLabor: {"development", "design"},
+			},
+		}, 1, 2, ErrLineItemBadSubtype},
+		{"description-length", Options{}, 1, 3, ErrLineItemFieldLength},
+		{"bad-token", Options{}, 1, 4, ErrLineItemBadToken},
+		{"labor-currency", Options{}, 1, 7, ErrLineItemBadCurrency},
+		{"bad-currency", Options{}, 1, 7, ErrLineItemBadCurrency},
+		{"bad-start-date", Options{}, 1, 8, ErrLineItemBadDate},
+		{"bad-end-date", Options{}, 1, 9, ErrLineItemBadDate},
+		{"end-before-start", Options{}, 1, 9, ErrLineItemBadDate},
+		{"start-outside-month", january, 1, 8, ErrLineItemBadDate},
+		{"end-outside-month", january, 1, 9, ErrLineItemBadDate},
+		{"header-line-number", Options{SkipHeader: true}, 2, 5,
+			ErrLineItemBadFloat},
+		{"credit-positive-cost", Options{}, 1, 6, ErrLineItemBadAmount},
+		{"credit-hours", Options{}, 1, 5, ErrLineItemBadAmount},
+		{"bad-duration-minutes", Options{}, 1, 5, ErrLineItemBadAmount},
+		{"bad-duration-format", Options{}, 1, 5, ErrLineItemBadAmount},
+		{"tag-length", Options{}, 1, 10, ErrLineItemFieldLength},
+		{"field-mapping-hours", Options{FieldMapping: testFieldMapping}, 1,
+			6, ErrLineItemBadFloat},
+		{"field-mapping-column", Options{FieldMapping: testFieldMapping}, 1,
+			0, ErrLineItemFieldCount},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data := readFixture(t, filepath.Join("invalid", test.name+".csv"))
+			_, err := ParseInvoiceCSV(data, test.opts)
+			lie, ok := err.(*LineItemError)
+			if !ok {
+				t.Fatalf("got error %v, want LineItemError", err)
+			}
+			if lie.Unwrap() != test.err {
+				t.Fatalf("got error %v, want %v", lie.Unwrap(), test.err)
+			}
+			if lie.Line != test.line || lie.Field != test.field {
+				t.Fatalf("got line %v field %v, want line %v field %v",
+					lie.Line, lie.Field, test.line, test.field)
+			}
+		})
+	}
+}
+
+func TestParseInvoiceCSVGoldenNoLineItems(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+	}{
+		{"empty", Options{}},
+		{"only-comments", Options{}},
+		{"header-only", Options{SkipHeader: true}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data := readFixture(t, filepath.Join("invalid", test.name+".csv"))
+			_, err := ParseInvoiceCSV(data, test.opts)
+			if err != ErrNoLineItems {
+				t.Fatalf("got error %v, want ErrNoLineItems", err)
+			}
+		})
+	}
+}
+
+func TestParseInvoiceCSVGoldenLineItemCount(t *testing.T) {
+	data := readFixture(t, filepath.Join("invalid", "too-many-line-items.csv"))
+	_, err := ParseInvoiceCSV(data, Options{MaxLineItems: 2})
+	lce, ok := err.(*LineItemCountError)
+	if !ok {
+		t.Fatalf("got error %v, want LineItemCountError", err)
+	}
+	if lce.Count != 3 || lce.Max != 2 {
+		t.Fatalf("got count %v max %v, want count 3 max 2", lce.Count,
+			lce.Max)
+	}
+
+	// The same csv is valid with the default maximum
+	_, err = ParseInvoiceCSV(data, Options{})
+	if err != nil {
+		t.Fatalf("ParseInvoiceCSV: %v", err)
+	}
+}
+
+func TestParseInvoiceCSVGoldenQuotes(t *testing.T) {
+	data := readFixture(t, filepath.Join("invalid", "bare-quote.csv"))
+	_, err := ParseInvoiceCSV(data, Options{})
+	pe, ok := err.(*csv.ParseError)
+	if !ok || pe.Err != csv.ErrBareQuote {
+		t.Fatalf("got error %v, want csv.ErrBareQuote", err)
+	}
+
+	// Stray quotes are accepted with lazy quotes
+	invInput, err := ParseInvoiceCSV(data, Options{LazyQuotes: true})
+	if err != nil {
+		t.Fatalf("ParseInvoiceCSV: %v", err)
+	}
+	if len(invInput.LineItems) != 1 {
+		t.Fatalf("got %v line items, want 1", len(invInput.LineItems))
+	}
+}
+
+func TestParseInvoiceCSVMultiLineDescription(t *testing.T) {
+	data := []byte("# Quoted descriptions may span multiple lines\n" +
+		"labor,development,\"Implemented the parser\n\n" +
+		"- line numbers\n- comments\",,10,400\n" +
+		"expense,hosting,\"Server hosting\nJanuary\",,,25\n")
+	invInput, err := ParseInvoiceCSV(data, Options{})
+	if err != nil {
+		t.Fatalf("ParseInvoiceCSV: %v", err)
+	}
+	want := []string{
+		"Implemented the parser\n\n- line numbers\n- comments",
+		"Server hosting\nJanuary",
+	}
+	if len(invInput.LineItems) != len(want) {
+		t.Fatalf("got %v line items, want %v", len(invInput.LineItems),
+			len(want))
+	}
+	for i, li := range invInput.LineItems {
+		if li.Description != want[i] {
+			t.Errorf("line item %v: got description %q, want %q", i,
+				li.Description, want[i])
+		}
+		if int(li.LineNumber) != i {
+			t.Errorf("line item %v: got line number %v", i, li.LineNumber)
+		}
+	}
+
+	// Errors report the line that the record starts on, both for a
+	// multi-line record and for the records that follow it.
+	tests := []struct {
+		name  string
+		csv   string
+		opts  Options
+		line  int
+		field int
+	}{
+		{"multi-line record", "labor,development,\"One\nTwo\nThree\",," +
+			"ten,400\n", Options{}, 1, 5},
+		{"after multi-line record", "labor,development,\"One\nTwo\",,1,40\n" +
+			"labor,development,Three,,ten,400\n", Options{}, 3, 5},
+		{"after header and comment", "type,subtype,description,token,hours," +
+			"cost\n# Comment\nlabor,development,\"One\nTwo\",,1,40\n" +
+			"expense,hosting,Server,,,abc\n", Options{SkipHeader: true}, 5, 6},
+	}
+	for _, test := range tests {
+		_, err := ParseInvoiceCSV([]byte(test.csv), test.opts)
+		lie, ok := err.(*LineItemError)
+		if !ok {
+			t.Errorf("%v: got error %v, want LineItemError", test.name, err)
+			continue
+		}
+		if lie.Line != test.line || lie.Field != test.field {
+			t.Errorf("%v: got line %v field %v, want line %v field %v",
+				test.name, lie.Line, lie.Field, test.line, test.field)
+		}
+	}
+}
+
+func TestParseInvoiceCSVLines(t *testing.T) {
+	tests := []struct {
+		name  string
+		csv   string
+		opts  Options
+		lines []int
+	}{
+		{"records", "labor,development,One,,1,40\n" +
+			"labor,development,Two,,1,40\n", Options{}, []int{1, 2}},
+		{"comment block", "# Invoice for January\n# Contractor: alice\n" +
+			"#\nlabor,development,One,,1,40\n" +
+			"labor,development,Two,,1,40\n", Options{}, []int{4, 5}},
+		{"blank lines", "\nlabor,development,One,,1,40\n\n\n" +
+			"labor,development,Two,,1,40\n", Options{}, []int{2, 5}},
+		{"header", "# Exported\ntype,subtype,description,token,hours," +
+			"cost\nlabor,development,One,,1,40\n", Options{SkipHeader: true},
+			[]int{3}},
+		{"quoted lines", "labor,development,\"One\n# Not a comment\n\n" +
+			"\"\"Two\"\"\",,1,40\nlabor,development,Three,,1,40\n", Options{},
+			[]int{1, 5}},
+		{"lazy quotes", "labor,development,Stray \"quote,,1,40\n" +
+			"labor,development,\"A \"lazy\" quote\",,1,40\n" +
+			"labor,development,Three,,1,40\n", Options{LazyQuotes: true},
+			[]int{1, 2, 3}},
+		{"tab delimited", "# Tabs\nlabor\tdevelopment\tOne\t\t1\t40\n",
+			Options{Delimiter: '\t'}, []int{2}},
+	}
+	for _, test := range tests {
+		invInput, lines, err := ParseInvoiceCSVLines([]byte(test.csv),
+			test.opts)
+		if err != nil {
+			t.Errorf("%v: ParseInvoiceCSVLines: %v", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(lines, test.lines) {
+			t.Errorf("%v: got lines %v, want %v", test.name, lines,
+				test.lines)
+		}
+		if len(lines) != len(invInput.LineItems) {
+			t.Errorf("%v: got %v lines for %v line items", test.name,
+				len(lines), len(invInput.LineItems))
+		}
+	}
+
+	// Errors report the line that follows the comment block
+	data := []byte("# Invoice for January\n# Contractor: alice\n\n" +
+		"labor,development,One,,1,40\nlabor,development,Two,,ten,40\n")
+	_, err := ParseInvoiceCSV(data, Options{})
+	lie, ok := err.(*LineItemError)
+	if !ok {
+		t.Fatalf("got error %v, want LineItemError", err)
+	}
+	if lie.Line != 5 || lie.Field != 5 {
+		t.Fatalf("got line %v field %v, want line 5 field 5", lie.Line,
+			lie.Field)
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package cmsutil
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+
+	cms "github.com/decred/politeia/politeiawww/api/cms/v1"
+)
+
+// InvoiceStatusTransitions contains the valid status transitions of an
+// invoice.  The status of an invoice that is not a key, e.g. a paid or
+// approved invoice, cannot be changed.  It is enforced by politeiawww and
+// used by clients to reject invalid transitions before the request is sent.
+var InvoiceStatusTransitions = map[cms.InvoiceStatusT][]cms.InvoiceStatusT{
+	// New invoices may only be updated to approved, rejected, disputed
+	// or withdrawn.
+	cms.InvoiceStatusNew: {
+		cms.InvoiceStatusApproved,
+		cms.InvoiceStatusRejected,
+		cms.InvoiceStatusDisputed,
+		cms.InvoiceStatusWithdrawn,
+	},
+	// Rejected invoices may only be updated to approved or updated.
+	cms.InvoiceStatusRejected: {
+		cms.InvoiceStatusApproved,
+		cms.InvoiceStatusUpdated,
+	},
+	// Updated invoices may only be updated to approved, rejected,
+	// disputed or withdrawn.
+	cms.InvoiceStatusUpdated: {
+		cms.InvoiceStatusApproved,
+		cms.InvoiceStatusRejected,
+		cms.InvoiceStatusDisputed,
+		cms.InvoiceStatusWithdrawn,
+	},
+}
+
+// ValidInvoiceStatusTransition returns whether an invoice may go from the old
+// status to the new status.
+func ValidInvoiceStatusTransition(oldStatus, newStatus cms.InvoiceStatusT) bool {
+	for _, v := range InvoiceStatusTransitions[oldStatus] {
+		if v == newStatus {
+			return true
+		}
+	}
+	return false
+}
+
+// InvoiceNonce returns the nonce of an invoice with the passed in merkle
+// root, month and year.  The client sends it with the new invoice request
+// and the server compares it against the nonce of the existing invoice for
+// the month so that a retried request is recognized.
+func InvoiceNonce(merkle string, month, year uint16) string {
+	h := sha256.Sum256([]byte(merkle + strconv.Itoa(int(month)) +
+		strconv.Itoa(int(year))))
+	return hex.EncodeToString(h[:])
+}
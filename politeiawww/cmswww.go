@@ -280,6 +280,10 @@ func (p *politeiawww) setCMSWWWRoutes() {
 		p.handleNewInvoice, permissionLogin)
 	p.addRoute(http.MethodPost, cms.RouteEditInvoice,
 		p.handleEditInvoice, permissionLogin)
+	// Admin privileges are checked by processSetInvoiceStatus since
+	// invoice authors may withdraw their own invoices.
+	p.addRoute(http.MethodPost, cms.RouteSetInvoiceStatus,
+		p.handleSetInvoiceStatus, permissionLogin)
 	p.addRoute(http.MethodGet, cms.RouteInvoiceDetails,
 		p.handleInvoiceDetails, permissionLogin)
 	p.addRoute(http.MethodGet, cms.RouteUserInvoices,
@@ -299,8 +303,6 @@ func (p *politeiawww) setCMSWWWRoutes() {
 		p.handleCensorComment, permissionAdmin)
 	p.addRoute(http.MethodPost, cms.RouteAdminInvoices,
 		p.handleAdminInvoices, permissionAdmin)
-	p.addRoute(http.MethodPost, cms.RouteSetInvoiceStatus,
-		p.handleSetInvoiceStatus, permissionAdmin)
 
 	// Routes for Contractor Management System
 
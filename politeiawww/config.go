@@ -129,6 +129,13 @@ type config struct {
 	CMSRootCert              string `long:"cmsrootcert" description:"File containing the CA certificate for the cmsdb"`
 	CMSCert                  string `long:"cmscert" description:"File containing the politeiawww client certificate for the cmsdb"`
 	CMSKey                   string `long:"cmskey" description:"File containing the politeiawww client certificate key for the cmsdb"`
+
+	// Invoice policy, only used in cmswww mode.
+	InvoiceSubtypes         []string `long:"invoicesubtype" description:"Allowed invoice line item subtype in the format type:subtype, e.g. labor:development -- May be specified multiple times; the subtypes of a line item type that is not listed are not restricted"`
+	InvoiceLineItemSubtypes map[string][]string
+	InvoiceWindow           bool `long:"invoicewindow" description:"Only accept invoices for the months from invoicemonthsbefore months before through invoicemonthsafter months after the current month"`
+	InvoiceMonthsBefore     uint `long:"invoicemonthsbefore" description:"Number of past months that invoices are accepted for when invoicewindow is set"`
+	InvoiceMonthsAfter      uint `long:"invoicemonthsafter" description:"Number of future months that invoices are accepted for when invoicewindow is set"`
 }
 
 // serviceOptions defines the configuration options for the rpc as a service
@@ -510,6 +517,11 @@ func loadConfig() (*config, []string, error) {
 		cfg.CMSCert = cleanAndExpandPath(cfg.CMSCert)
 		cfg.CMSKey = cleanAndExpandPath(cfg.CMSKey)
 
+		cfg.InvoiceLineItemSubtypes, err = parseInvoiceSubtypes(cfg.InvoiceSubtypes)
+		if err != nil {
+			return nil, nil, err
+		}
+
 	case politeiaWWWMode:
 	default:
 		err := fmt.Errorf("invalid mode: %v", cfg.Mode)
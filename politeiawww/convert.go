@@ -551,6 +551,7 @@ func convertRecordToDatabaseInvoice(p pd.Record) (*cmsdatabase.Invoice, error) {
 			dbInvoice.Timestamp = mdGeneral.Timestamp
 			dbInvoice.PublicKey = mdGeneral.PublicKey
 			dbInvoice.UserSignature = mdGeneral.Signature
+			dbInvoice.UserID = mdGeneral.UserID
 			dbInvoice.LineItems, err = parseJSONFileToLineItems(p.CensorshipRecord.Token, dbInvoice.Files[0])
 			if err != nil {
 				return nil, fmt.Errorf("could not parse invoice csv data for token '%v': %v",
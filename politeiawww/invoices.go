@@ -10,10 +10,12 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/decred/dcrtime/merkle"
 	pd "github.com/decred/politeia/politeiad/api/v1"
@@ -22,34 +24,10 @@ import (
 	cms "github.com/decred/politeia/politeiawww/api/cms/v1"
 	www "github.com/decred/politeia/politeiawww/api/www/v1"
 	database "github.com/decred/politeia/politeiawww/cmsdatabase"
+	"github.com/decred/politeia/politeiawww/cmsutil"
 	"github.com/decred/politeia/politeiawww/user"
 	"github.com/decred/politeia/util"
-)
-
-var (
-	// This covers the possible valid status transitions for any invoices.  If
-	// a current invoice's status does not fall into these 3 categories, then
-	// an admin will not be able to update their status.  For example,
-	// paid or approved invoices cannot have their status changed.
-	validStatusTransitions = map[cms.InvoiceStatusT][]cms.InvoiceStatusT{
-		// New invoices may only be updated to approved, rejected or disputed.
-		cms.InvoiceStatusNew: {
-			cms.InvoiceStatusApproved,
-			cms.InvoiceStatusRejected,
-			cms.InvoiceStatusDisputed,
-		},
-		// Rejected invoices may only be updated to approved or updated.
-		cms.InvoiceStatusRejected: {
-			cms.InvoiceStatusApproved,
-			cms.InvoiceStatusUpdated,
-		},
-		// Updated invoices may only be updated to approved, rejected or disputed.
-		cms.InvoiceStatusUpdated: {
-			cms.InvoiceStatusApproved,
-			cms.InvoiceStatusRejected,
-			cms.InvoiceStatusDisputed,
-		},
-	}
+	"github.com/google/uuid"
 )
 
 const (
@@ -64,12 +42,83 @@ const (
 func (p *politeiawww) processNewInvoice(ni cms.NewInvoice, u *user.User) (*cms.NewInvoiceReply, error) {
 	log.Tracef("processNewInvoice")
 
-	err := validateInvoice(ni, u)
+	err := p.validateInvoice(ni, u)
 	if err != nil {
 		return nil, err
 	}
 
-	name := strconv.Itoa(int(ni.Year)) + strconv.Itoa(int(ni.Month)) + u.Username
+	if !invoiceMonthInWindow(ni.Month, ni.Year, time.Now(), p.invoiceWindow()) {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusInvoiceMonthNotAccepted,
+		}
+	}
+
+	// An admin may submit an invoice on behalf of a contractor.  The
+	// invoice signature is verified against the admin identity above
+	// and the invoice is attributed to the contractor.
+	author := u
+	if ni.OnBehalfOf != "" {
+		if !u.Admin {
+			return nil, www.UserError{
+				ErrorCode: www.ErrorStatusInvoiceProxyNotAdmin,
+			}
+		}
+		id, err := uuid.Parse(ni.OnBehalfOf)
+		if err != nil {
+			return nil, www.UserError{
+				ErrorCode: www.ErrorStatusUserNotFound,
+			}
+		}
+		author, err = p.db.UserGetById(id)
+		if err != nil {
+			if err == user.ErrUserNotFound {
+				err = www.UserError{
+					ErrorCode: www.ErrorStatusUserNotFound,
+				}
+			}
+			return nil, err
+		}
+		log.Infof("Invoice submitted by admin %v on behalf of %v",
+			u.Username, author.Username)
+	}
+
+	// Only one invoice may be submitted per month.  Withdrawn and
+	// rejected invoices don't count so that the contractor can submit a
+	// new invoice in their place.  A request with the nonce of the
+	// existing invoice is a retry of the request that created it, so
+	// the existing token is returned as error context which allows the
+	// client to recover the censorship record.
+	dbInvs, err := p.cmsDB.InvoicesByUserID(author.ID.String())
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range dbInvs {
+		if v.Month != ni.Month || v.Year != ni.Year {
+			continue
+		}
+		switch v.Status {
+		case cms.InvoiceStatusWithdrawn, cms.InvoiceStatusRejected:
+			continue
+		}
+		ue := www.UserError{
+			ErrorCode: www.ErrorStatusInvoiceDuplicate,
+		}
+		if ni.Nonce != "" {
+			invRec, err := p.getInvoice(v.Token)
+			if err != nil {
+				return nil, err
+			}
+			nonce := cmsutil.InvoiceNonce(invRec.CensorshipRecord.Merkle,
+				v.Month, v.Year)
+			if ni.Nonce == nonce {
+				ue.ErrorContext = []string{v.Token}
+			}
+		}
+		return nil, ue
+	}
+
+	name := strconv.Itoa(int(ni.Year)) + strconv.Itoa(int(ni.Month)) +
+		author.Username
 
 	md, err := encodeBackendInvoiceMetadata(BackendInvoiceMetadata{
 		Version:   BackendInvoiceMetadataVersion,
@@ -79,6 +128,7 @@ func (p *politeiawww) processNewInvoice(ni cms.NewInvoice, u *user.User) (*cms.N
 		Signature: ni.Signature,
 		Month:     ni.Month,
 		Year:      ni.Year,
+		UserID:    author.ID.String(),
 	})
 	if err != nil {
 		return nil, err
@@ -201,8 +251,6 @@ func (p *politeiawww) processNewInvoice(ni cms.NewInvoice, u *user.User) (*cms.N
 	if err != nil {
 		return nil, err
 	}
-	// Set UserID for current user
-	ir.UserID = u.ID.String()
 	ir.Status = cms.InvoiceStatusNew
 
 	err = p.cmsDB.NewInvoice(ir)
@@ -216,7 +264,7 @@ func (p *politeiawww) processNewInvoice(ni cms.NewInvoice, u *user.User) (*cms.N
 		EventDataProposalSubmitted{
 			CensorshipRecord: &cr,
 			ProposalName:     name,
-			User:             u,
+			User:             author,
 		},
 	)
 
@@ -225,7 +273,7 @@ func (p *politeiawww) processNewInvoice(ni cms.NewInvoice, u *user.User) (*cms.N
 	}, nil
 }
 
-func validateInvoice(ni cms.NewInvoice, u *user.User) error {
+func (p *politeiawww) validateInvoice(ni cms.NewInvoice, u *user.User) error {
 	log.Tracef("validateInvoice")
 
 	// Obtain signature
@@ -259,9 +307,9 @@ func validateInvoice(ni cms.NewInvoice, u *user.User) error {
 	filenames := make(map[string]int, len(ni.Files))
 	// Check that the file number policy is followed.
 	var (
-		numCSVs, numImages, numInvoiceFiles    int
-		csvExceedsMaxSize, imageExceedsMaxSize bool
-		hashes                                 []*[sha256.Size]byte
+		numCSVs, numImages, numPDFs, numInvoiceFiles              int
+		csvExceedsMaxSize, imageExceedsMaxSize, pdfExceedsMaxSize bool
+		hashes                                                    []*[sha256.Size]byte
 	)
 	for _, v := range ni.Files {
 		filenames[v.Name]++
@@ -269,7 +317,17 @@ func validateInvoice(ni cms.NewInvoice, u *user.User) error {
 			data []byte
 			err  error
 		)
-		if strings.HasPrefix(v.MIME, "image/") {
+		switch {
+		case v.MIME == "application/pdf":
+			numPDFs++
+			data, err = base64.StdEncoding.DecodeString(v.Payload)
+			if err != nil {
+				return err
+			}
+			if len(data) > www.PolicyMaxPDFSize {
+				pdfExceedsMaxSize = true
+			}
+		case strings.HasPrefix(v.MIME, "image/"):
 			numImages++
 			data, err = base64.StdEncoding.DecodeString(v.Payload)
 			if err != nil {
@@ -278,7 +336,7 @@ func validateInvoice(ni cms.NewInvoice, u *user.User) error {
 			if len(data) > www.PolicyMaxImageSize {
 				imageExceedsMaxSize = true
 			}
-		} else {
+		default:
 			numCSVs++
 
 			if v.Name == invoiceFile {
@@ -301,6 +359,21 @@ func validateInvoice(ni cms.NewInvoice, u *user.User) error {
 					ErrorCode: www.ErrorStatusMalformedInvoiceFile,
 				}
 			}
+			if invInput.ExchangeRate < 0 {
+				return www.UserError{
+					ErrorCode:    www.ErrorStatusMalformedInvoiceFile,
+					ErrorContext: []string{"exchangerate must be positive"},
+				}
+			}
+			err = validateLineItemLengths(invInput.LineItems)
+			if err != nil {
+				return err
+			}
+			err = validateLineItemSubtypes(invInput.LineItems,
+				p.cfg.InvoiceLineItemSubtypes)
+			if err != nil {
+				return err
+			}
 
 		}
 
@@ -347,6 +420,12 @@ func validateInvoice(ni cms.NewInvoice, u *user.User) error {
 		}
 	}
 
+	if numPDFs > www.PolicyMaxPDFs {
+		return www.UserError{
+			ErrorCode: www.ErrorStatusMaxPDFsExceededPolicy,
+		}
+	}
+
 	if csvExceedsMaxSize {
 		return www.UserError{
 			ErrorCode: www.ErrorStatusMaxMDSizeExceededPolicy,
@@ -359,6 +438,12 @@ func validateInvoice(ni cms.NewInvoice, u *user.User) error {
 		}
 	}
 
+	if pdfExceedsMaxSize {
+		return www.UserError{
+			ErrorCode: www.ErrorStatusMaxPDFSizeExceededPolicy,
+		}
+	}
+
 	// Note that we need validate the string representation of the merkle
 	mr := merkle.Root(hashes)
 	if !pk.VerifyMessage([]byte(hex.EncodeToString(mr[:])), sig) {
@@ -370,6 +455,133 @@ func validateInvoice(ni cms.NewInvoice, u *user.User) error {
 	return nil
 }
 
+// validateLineItemLengths verifies that the subtype and description of each
+// line item do not exceed the invoice policy maximum lengths.  The lengths are
+// counted in characters.
+func validateLineItemLengths(lineItems []cms.LineItemsInput) error {
+	for i, li := range lineItems {
+		if utf8.RuneCountInString(li.Subtype) >
+			www.PolicyInvoiceMaxSubtypeLength {
+			return www.UserError{
+				ErrorCode: www.ErrorStatusMalformedInvoiceFile,
+				ErrorContext: []string{fmt.Sprintf("line item %v: subtype "+
+					"exceeds the maximum length of %v", i+1,
+					www.PolicyInvoiceMaxSubtypeLength)},
+			}
+		}
+		if utf8.RuneCountInString(li.Description) >
+			www.PolicyInvoiceMaxDescriptionLength {
+			return www.UserError{
+				ErrorCode: www.ErrorStatusMalformedInvoiceFile,
+				ErrorContext: []string{fmt.Sprintf("line item %v: "+
+					"description exceeds the maximum length of %v", i+1,
+					www.PolicyInvoiceMaxDescriptionLength)},
+			}
+		}
+	}
+	return nil
+}
+
+// invoiceWindow returns the range of months that invoices are accepted for.
+// It returns nil when invoices are accepted for any month.
+func (p *politeiawww) invoiceWindow() *www.InvoiceWindow {
+	if !p.cfg.InvoiceWindow {
+		return nil
+	}
+	return &www.InvoiceWindow{
+		MonthsBefore: p.cfg.InvoiceMonthsBefore,
+		MonthsAfter:  p.cfg.InvoiceMonthsAfter,
+	}
+}
+
+// invoiceMonthInWindow returns whether the passed in month and year fall
+// within the invoice window relative to the month of now.  Any month is
+// accepted when the window is nil.
+func invoiceMonthInWindow(month, year uint16, now time.Time, w *www.InvoiceWindow) bool {
+	if w == nil {
+		return true
+	}
+	current := now.Year()*12 + int(now.Month()) - 1
+	requested := int(year)*12 + int(month) - 1
+	return requested >= current-int(w.MonthsBefore) &&
+		requested <= current+int(w.MonthsAfter)
+}
+
+// invoiceLineItemTypeNames contains the names of the line item types that are
+// used by the invoicesubtype config option and the policy.
+var invoiceLineItemTypeNames = map[cms.LineItemTypeT]string{
+	cms.LineItemTypeLabor:   "labor",
+	cms.LineItemTypeExpense: "expense",
+	cms.LineItemTypeMisc:    "misc",
+	cms.LineItemTypeCredit:  "credit",
+}
+
+// parseInvoiceSubtypes parses the allowed line item subtypes from the
+// invoicesubtype config options, which are in the format type:subtype.  The
+// returned subtypes are keyed by line item type name.
+func parseInvoiceSubtypes(options []string) (map[string][]string, error) {
+	if len(options) == 0 {
+		return nil, nil
+	}
+
+	subtypes := make(map[string][]string)
+	for _, v := range options {
+		s := strings.SplitN(v, ":", 2)
+		if len(s) != 2 || s[1] == "" {
+			return nil, fmt.Errorf("invalid invoicesubtype %q: must be "+
+				"in the format type:subtype", v)
+		}
+		name := strings.ToLower(s[0])
+		var valid bool
+		for _, n := range invoiceLineItemTypeNames {
+			if n == name {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return nil, fmt.Errorf("invalid invoicesubtype %q: unknown "+
+				"line item type %v", v, s[0])
+		}
+		if utf8.RuneCountInString(s[1]) > www.PolicyInvoiceMaxSubtypeLength {
+			return nil, fmt.Errorf("invalid invoicesubtype %q: subtype "+
+				"exceeds the maximum length of %v", v,
+				www.PolicyInvoiceMaxSubtypeLength)
+		}
+		subtypes[name] = append(subtypes[name], s[1])
+	}
+
+	return subtypes, nil
+}
+
+// validateLineItemSubtypes ensures that the subtypes of the passed in line
+// items are allowed.  The subtypes of a line item type that has no allowed
+// subtypes are not restricted.
+func validateLineItemSubtypes(lineItems []cms.LineItemsInput, subtypes map[string][]string) error {
+	for i, li := range lineItems {
+		name := invoiceLineItemTypeNames[li.Type]
+		allowed, ok := subtypes[name]
+		if !ok {
+			continue
+		}
+		var found bool
+		for _, v := range allowed {
+			if v == li.Subtype {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return www.UserError{
+				ErrorCode: www.ErrorStatusMalformedInvoiceFile,
+				ErrorContext: []string{fmt.Sprintf("line item %v: %q is "+
+					"not an allowed %v subtype", i+1, li.Subtype, name)},
+			}
+		}
+	}
+	return nil
+}
+
 // processInvoiceDetails fetches a specific proposal version from the records
 // cache and returns it.
 func (p *politeiawww) processInvoiceDetails(invDetails cms.InvoiceDetails, user *user.User) (*cms.InvoiceDetailsReply, error) {
@@ -409,6 +621,21 @@ func (p *politeiawww) processSetInvoiceStatus(sis cms.SetInvoiceStatus,
 		}
 		return nil, err
 	}
+
+	// Invoices may only be withdrawn by their author.  All other status
+	// changes require admin privileges.
+	if sis.Status == cms.InvoiceStatusWithdrawn {
+		if dbInvoice.UserID != u.ID.String() {
+			return nil, www.UserError{
+				ErrorCode: www.ErrorStatusUserNotAuthor,
+			}
+		}
+	} else if !u.Admin {
+		return nil, www.UserError{
+			ErrorCode: www.ErrorStatusUserActionNotAllowed,
+		}
+	}
+
 	err = validateStatusTransition(dbInvoice.Status, sis.Status, sis.Reason)
 	if err != nil {
 		return nil, err
@@ -422,7 +649,11 @@ func (p *politeiawww) processSetInvoiceStatus(sis cms.SetInvoiceStatus,
 		Reason:    sis.Reason,
 	}
 
-	changes.AdminPublicKey = u.ActiveIdentity().String()
+	// Only changes that are made by an admin record the admin identity.
+	// An author withdrawing their own invoice is not an admin action.
+	if u.Admin {
+		changes.AdminPublicKey = u.ActiveIdentity().String()
+	}
 
 	blob, err := json.Marshal(changes)
 	if err != nil {
@@ -497,15 +728,14 @@ func validateStatusTransition(
 	newStatus cms.InvoiceStatusT,
 	reason string,
 ) error {
-	validStatuses, ok := validStatusTransitions[oldStatus]
-	if !ok {
+	if _, ok := cmsutil.InvoiceStatusTransitions[oldStatus]; !ok {
 		log.Errorf("status not supported: %v", oldStatus)
 		return www.UserError{
 			ErrorCode: www.ErrorStatusInvalidInvoiceStatusTransition,
 		}
 	}
 
-	if !statusInSlice(validStatuses, newStatus) {
+	if !cmsutil.ValidInvoiceStatusTransition(oldStatus, newStatus) {
 		return www.UserError{
 			ErrorCode: www.ErrorStatusInvalidInvoiceStatusTransition,
 		}
@@ -520,16 +750,6 @@ func validateStatusTransition(
 	return nil
 }
 
-func statusInSlice(arr []cms.InvoiceStatusT, status cms.InvoiceStatusT) bool {
-	for _, s := range arr {
-		if status == s {
-			return true
-		}
-	}
-
-	return false
-}
-
 // processEditInvoice attempts to edit a proposal on politeiad.
 func (p *politeiawww) processEditInvoice(ei cms.EditInvoice, u *user.User) (*cms.EditInvoiceReply, error) {
 	log.Tracef("processEditInvoice %v", ei.Token)
@@ -569,7 +789,7 @@ func (p *politeiawww) processEditInvoice(ei cms.EditInvoice, u *user.User) (*cms
 		PublicKey: ei.PublicKey,
 		Signature: ei.Signature,
 	}
-	err = validateInvoice(ni, u)
+	err = p.validateInvoice(ni, u)
 	if err != nil {
 		return nil, err
 	}
@@ -581,6 +801,7 @@ func (p *politeiawww) processEditInvoice(ei cms.EditInvoice, u *user.User) (*cms
 		Year:      invRec.Year,
 		PublicKey: ei.PublicKey,
 		Signature: ei.Signature,
+		UserID:    invRec.UserID,
 	}
 	md, err := encodeBackendInvoiceMetadata(backendMetadata)
 	if err != nil {
@@ -702,10 +923,45 @@ func (p *politeiawww) getInvoice(token string) (*cms.InvoiceRecord, error) {
 	invRec.Files = pr.Files
 	invRec.CensorshipRecord = pr.CensorshipRecord
 	invRec.Signature = pr.Signature
+	invRec.StatusChanges = p.invoiceStatusChanges(*r)
 
 	return invRec, nil
 }
 
+// invoiceStatusChanges returns the status changes that are recorded in the
+// metadata of the passed in invoice record.  The username of the admin that
+// made a change is looked up by public key.
+func (p *politeiawww) invoiceStatusChanges(r cache.Record) []cms.InvoiceStatusChange {
+	var changes []cms.InvoiceStatusChange
+	for _, ms := range r.Metadata {
+		if ms.ID != mdStreamChanges {
+			continue
+		}
+		mdc, err := decodeBackendInvoiceMDChanges([]byte(ms.Payload))
+		if err != nil {
+			log.Errorf("invoiceStatusChanges: decode BackendInvoiceMDChange "+
+				"'%v' token '%v': %v", ms, r.CensorshipRecord.Token, err)
+			continue
+		}
+		for _, v := range mdc {
+			c := cms.InvoiceStatusChange{
+				Status:         v.NewStatus,
+				Reason:         v.Reason,
+				AdminPublicKey: v.AdminPublicKey,
+				Timestamp:      v.Timestamp,
+			}
+			if v.AdminPublicKey != "" {
+				userID, ok := p.getUserIDByPubKey(v.AdminPublicKey)
+				if ok {
+					c.AdminUsername = p.getUsernameById(userID)
+				}
+			}
+			changes = append(changes, c)
+		}
+	}
+	return changes
+}
+
 // processUserInvoices fetches all invoices that are currently stored in the
 // cmsdb for the logged in user.
 func (p *politeiawww) processUserInvoices(user *user.User) (*cms.UserInvoicesReply, error) {
@@ -830,6 +1086,11 @@ type BackendInvoiceMetadata struct {
 	PublicKey string `json:"publickey"` // Key used for signature.
 	Signature string `json:"signature"` // Signature of merkle root
 	Name      string `json:"name"`      // Generated invoice name
+
+	// UserID is the ID of the invoice author.  It differs from the
+	// owner of PublicKey when an admin submitted the invoice on behalf
+	// of a contractor.
+	UserID string `json:"userid,omitempty"`
 }
 
 // BackendInvoiceMDChange is the metadata for updating Records on politeiad.
@@ -888,3 +1149,25 @@ func decodeBackendInvoiceMDChange(payload []byte) (*BackendInvoiceMDChange, erro
 
 	return &md, nil
 }
+
+// decodeBackendInvoiceMDChanges decodes a JSON byte slice that contains the
+// appended BackendInvoiceMDChange records of an invoice into a slice of
+// BackendInvoiceMDChange.
+func decodeBackendInvoiceMDChanges(payload []byte) ([]BackendInvoiceMDChange, error) {
+	var mdc []BackendInvoiceMDChange
+
+	d := json.NewDecoder(strings.NewReader(string(payload)))
+	for {
+		var md BackendInvoiceMDChange
+		err := d.Decode(&md)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		mdc = append(mdc, md)
+	}
+
+	return mdc, nil
+}
@@ -0,0 +1,658 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/decred/dcrtime/merkle"
+	"github.com/decred/politeia/politeiad/api/v1/identity"
+	"github.com/decred/politeia/politeiad/cache"
+	cms "github.com/decred/politeia/politeiawww/api/cms/v1"
+	www "github.com/decred/politeia/politeiawww/api/www/v1"
+	"github.com/decred/politeia/politeiawww/cmsdatabase"
+	"github.com/decred/politeia/politeiawww/cmsutil"
+	"github.com/decred/politeia/politeiawww/user"
+	"github.com/decred/politeia/util"
+	"github.com/google/uuid"
+)
+
+// createNewInvoice returns a NewInvoice for the given month and year that
+// contains a single labor line item and is signed with the given identity.
+func createNewInvoice(t *testing.T, id *identity.FullIdentity, month, year uint16) *cms.NewInvoice {
+	t.Helper()
+
+	return createNewInvoiceLineItems(t, id, month, year,
+		[]cms.LineItemsInput{
+			{
+				Type:        cms.LineItemTypeLabor,
+				Subtype:     "development",
+				Description: "politeiawww invoice tests",
+				Hours:       10,
+			},
+		})
+}
+
+// createNewInvoiceLineItems returns a NewInvoice for the given month and year
+// that contains the given line items and is signed with the given identity.
+func createNewInvoiceLineItems(t *testing.T, id *identity.FullIdentity, month, year uint16, lineItems []cms.LineItemsInput) *cms.NewInvoice {
+	t.Helper()
+
+	b, err := json.Marshal(cms.InvoiceInput{
+		Month:     month,
+		Year:      year,
+		LineItems: lineItems,
+	})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	f := www.File{
+		Name:    invoiceFile,
+		MIME:    "text/plain; charset=utf-8",
+		Digest:  hex.EncodeToString(util.Digest(b)),
+		Payload: base64.StdEncoding.EncodeToString(b),
+	}
+
+	// Compute and sign merkle
+	d, ok := util.ConvertDigest(f.Digest)
+	if !ok {
+		t.Fatalf("could not convert digest %v", f.Digest)
+	}
+	root := hex.EncodeToString(merkle.Root([]*[sha256.Size]byte{&d})[:])
+	sig := id.SignMessage([]byte(root))
+
+	return &cms.NewInvoice{
+		Month:     month,
+		Year:      year,
+		Files:     []www.File{f},
+		PublicKey: hex.EncodeToString(id.Public.Key[:]),
+		Signature: hex.EncodeToString(sig[:]),
+	}
+}
+
+// createSetInvoiceStatus returns a SetInvoiceStatus that is signed with the
+// given identity.
+func createSetInvoiceStatus(t *testing.T, id *identity.FullIdentity, token string, status cms.InvoiceStatusT, reason string) *cms.SetInvoiceStatus {
+	t.Helper()
+
+	msg := token + strconv.FormatUint(uint64(status), 10) + reason
+	sig := id.SignMessage([]byte(msg))
+
+	return &cms.SetInvoiceStatus{
+		Token:     token,
+		Status:    status,
+		Reason:    reason,
+		PublicKey: hex.EncodeToString(id.Public.Key[:]),
+		Signature: hex.EncodeToString(sig[:]),
+	}
+}
+
+func TestValidateInvoiceLineItemLengths(t *testing.T) {
+	// Setup politeiawww and a test user
+	p := newTestCMSPoliteiawww(t)
+	defer cleanupTestPoliteiawww(t, p)
+
+	usr, id := newUser(t, p, true, false)
+
+	lineItem := func(subtype, description string) []cms.LineItemsInput {
+		return []cms.LineItemsInput{
+			{
+				Type:        cms.LineItemTypeLabor,
+				Subtype:     subtype,
+				Description: description,
+				Hours:       10,
+			},
+		}
+	}
+	maxSubtype := strings.Repeat("s", www.PolicyInvoiceMaxSubtypeLength)
+	maxDesc := strings.Repeat("d", www.PolicyInvoiceMaxDescriptionLength)
+
+	// Setup tests
+	var tests = []struct {
+		name      string
+		lineItems []cms.LineItemsInput
+		want      error
+	}{
+		{"max lengths", lineItem(maxSubtype, maxDesc), nil},
+
+		// The lengths are counted in characters, not bytes
+		{"multibyte max lengths",
+			lineItem(strings.Repeat("é", www.PolicyInvoiceMaxSubtypeLength),
+				strings.Repeat("é", www.PolicyInvoiceMaxDescriptionLength)),
+			nil},
+
+		{"subtype too long",
+			lineItem(maxSubtype+"s", maxDesc),
+			www.UserError{
+				ErrorCode: www.ErrorStatusMalformedInvoiceFile,
+			}},
+
+		{"description too long",
+			lineItem(maxSubtype, maxDesc+"d"),
+			www.UserError{
+				ErrorCode: www.ErrorStatusMalformedInvoiceFile,
+			}},
+	}
+
+	// Run tests
+	for _, v := range tests {
+		t.Run(v.name, func(t *testing.T) {
+			ni := createNewInvoiceLineItems(t, id, 1, 2019, v.lineItems)
+			err := p.validateInvoice(*ni, usr)
+			got := errToStr(err)
+			want := errToStr(v.want)
+			if got != want {
+				t.Errorf("got error %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestParseInvoiceSubtypes(t *testing.T) {
+	maxSubtype := strings.Repeat("s", www.PolicyInvoiceMaxSubtypeLength)
+
+	var tests = []struct {
+		name    string
+		options []string
+		want    map[string][]string
+		wantErr bool
+	}{
+		{"no options", nil, nil, false},
+		{"subtypes",
+			[]string{"labor:development", "Labor:design", "expense:travel"},
+			map[string][]string{
+				"labor":   {"development", "design"},
+				"expense": {"travel"},
+			},
+			false},
+		{"subtype with colon", []string{"misc:a:b"},
+			map[string][]string{"misc": {"a:b"}}, false},
+		{"max subtype length", []string{"labor:" + maxSubtype},
+			map[string][]string{"labor": {maxSubtype}}, false},
+		{"missing subtype", []string{"labor:"}, nil, true},
+		{"missing separator", []string{"labor"}, nil, true},
+		{"unknown type", []string{"bonus:holiday"}, nil, true},
+		{"subtype too long", []string{"labor:" + maxSubtype + "s"}, nil, true},
+	}
+
+	for _, v := range tests {
+		t.Run(v.name, func(t *testing.T) {
+			got, err := parseInvoiceSubtypes(v.options)
+			if (err != nil) != v.wantErr {
+				t.Fatalf("got error %v, want error %v", err, v.wantErr)
+			}
+			if !reflect.DeepEqual(got, v.want) {
+				t.Errorf("got %v, want %v", got, v.want)
+			}
+		})
+	}
+}
+
+func TestValidateInvoiceLineItemSubtypes(t *testing.T) {
+	// Setup politeiawww and a test user
+	p := newTestCMSPoliteiawww(t)
+	defer cleanupTestPoliteiawww(t, p)
+
+	usr, id := newUser(t, p, true, false)
+
+	p.cfg.InvoiceLineItemSubtypes = map[string][]string{
+		"labor": {"development", "design"},
+	}
+
+	lineItem := func(typ cms.LineItemTypeT, subtype string) []cms.LineItemsInput {
+		return []cms.LineItemsInput{
+			{
+				Type:        typ,
+				Subtype:     subtype,
+				Description: "description",
+				Hours:       10,
+			},
+		}
+	}
+
+	// Setup tests
+	var tests = []struct {
+		name      string
+		lineItems []cms.LineItemsInput
+		want      error
+	}{
+		{"allowed subtype",
+			lineItem(cms.LineItemTypeLabor, "design"), nil},
+
+		// Subtypes are only restricted for the listed types
+		{"unrestricted type",
+			lineItem(cms.LineItemTypeExpense, "travel"), nil},
+
+		{"subtype not allowed",
+			lineItem(cms.LineItemTypeLabor, "travel"),
+			www.UserError{
+				ErrorCode: www.ErrorStatusMalformedInvoiceFile,
+			}},
+
+		// Subtypes are case sensitive
+		{"subtype case",
+			lineItem(cms.LineItemTypeLabor, "Design"),
+			www.UserError{
+				ErrorCode: www.ErrorStatusMalformedInvoiceFile,
+			}},
+	}
+
+	// Run tests
+	for _, v := range tests {
+		t.Run(v.name, func(t *testing.T) {
+			ni := createNewInvoiceLineItems(t, id, 1, 2019, v.lineItems)
+			err := p.validateInvoice(*ni, usr)
+			got := errToStr(err)
+			want := errToStr(v.want)
+			if got != want {
+				t.Errorf("got error %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestInvoiceMonthInWindow(t *testing.T) {
+	now := time.Date(2019, time.January, 15, 0, 0, 0, 0, time.UTC)
+	window := &www.InvoiceWindow{
+		MonthsBefore: 1,
+		MonthsAfter:  0,
+	}
+
+	var tests = []struct {
+		name   string
+		month  uint16
+		year   uint16
+		window *www.InvoiceWindow
+		want   bool
+	}{
+		{"no window", 1, 2000, nil, true},
+		{"current month", 1, 2019, window, true},
+		{"previous month across years", 12, 2018, window, true},
+		{"too far in the past", 11, 2018, window, false},
+		{"too far in the future", 2, 2019, window, false},
+		{"current month only", 1, 2019, &www.InvoiceWindow{}, true},
+		{"current month only, next month", 2, 2019,
+			&www.InvoiceWindow{}, false},
+	}
+
+	for _, v := range tests {
+		t.Run(v.name, func(t *testing.T) {
+			got := invoiceMonthInWindow(v.month, v.year, now, v.window)
+			if got != v.want {
+				t.Errorf("got %v, want %v", got, v.want)
+			}
+		})
+	}
+}
+
+func TestProcessNewInvoiceWindow(t *testing.T) {
+	// Setup politeiawww and a test user
+	p := newTestCMSPoliteiawww(t)
+	defer cleanupTestPoliteiawww(t, p)
+
+	usr, id := newUser(t, p, true, false)
+
+	// Only accept invoices for the current month
+	p.cfg.InvoiceWindow = true
+	now := time.Now()
+	month, year := uint16(now.Month()), uint16(now.Year())
+	prevMonth, prevYear := month-1, year
+	if prevMonth == 0 {
+		prevMonth, prevYear = 12, year-1
+	}
+
+	_, err := p.processNewInvoice(*createNewInvoice(t, id, prevMonth,
+		prevYear), usr)
+	got := errToStr(err)
+	want := errToStr(www.UserError{
+		ErrorCode: www.ErrorStatusInvoiceMonthNotAccepted,
+	})
+	if got != want {
+		t.Errorf("previous month: got error %v, want %v", got, want)
+	}
+
+	_, err = p.processNewInvoice(*createNewInvoice(t, id, month, year), usr)
+	if err != nil {
+		t.Errorf("current month: got error %v, want nil", err)
+	}
+}
+
+func TestValidMIMETypes(t *testing.T) {
+	hasPDF := func(types []string) bool {
+		for _, v := range types {
+			if v == "application/pdf" {
+				return true
+			}
+		}
+		return false
+	}
+	if hasPDF(validMIMETypes(politeiaWWWMode)) {
+		t.Errorf("piwww mode advertises application/pdf")
+	}
+	if !hasPDF(validMIMETypes(cmsWWWMode)) {
+		t.Errorf("cmswww mode does not advertise application/pdf")
+	}
+}
+
+func TestProcessSetInvoiceStatus(t *testing.T) {
+	// Setup politeiawww and a politeiad stand-in
+	p := newTestCMSPoliteiawww(t)
+	defer cleanupTestPoliteiawww(t, p)
+
+	srv := newTestPoliteiad(t, p)
+	defer srv.Close()
+
+	author, authorID := newUser(t, p, true, false)
+	other, otherID := newUser(t, p, true, false)
+	admin, adminID := newUser(t, p, true, true)
+
+	// Create test invoices
+	tokenWithdraw := "withdraw"
+	tokenApprove := "approve"
+	for _, token := range []string{tokenWithdraw, tokenApprove} {
+		err := p.cmsDB.NewInvoice(&cmsdatabase.Invoice{
+			Token:  token,
+			UserID: author.ID.String(),
+			Month:  1,
+			Year:   2019,
+			Status: cms.InvoiceStatusNew,
+		})
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+	}
+
+	// Setup tests.  The tests that succeed change the status of the
+	// invoice so they are run after the tests that fail.
+	var tests = []struct {
+		name         string
+		sis          *cms.SetInvoiceStatus
+		user         *user.User
+		want         error
+		wantAdminKey string
+	}{
+		{"withdraw by another user",
+			createSetInvoiceStatus(t, otherID, tokenWithdraw,
+				cms.InvoiceStatusWithdrawn, ""),
+			other,
+			www.UserError{
+				ErrorCode: www.ErrorStatusUserNotAuthor,
+			}, ""},
+
+		{"withdraw by an admin",
+			createSetInvoiceStatus(t, adminID, tokenWithdraw,
+				cms.InvoiceStatusWithdrawn, ""),
+			admin,
+			www.UserError{
+				ErrorCode: www.ErrorStatusUserNotAuthor,
+			}, ""},
+
+		{"approve by the author",
+			createSetInvoiceStatus(t, authorID, tokenApprove,
+				cms.InvoiceStatusApproved, ""),
+			author,
+			www.UserError{
+				ErrorCode: www.ErrorStatusUserActionNotAllowed,
+			}, ""},
+
+		{"reject without a reason",
+			createSetInvoiceStatus(t, adminID, tokenApprove,
+				cms.InvoiceStatusRejected, ""),
+			admin,
+			www.UserError{
+				ErrorCode: www.ErrorStatusReasonNotProvided,
+			}, ""},
+
+		{"withdraw by the author",
+			createSetInvoiceStatus(t, authorID, tokenWithdraw,
+				cms.InvoiceStatusWithdrawn, ""),
+			author, nil, ""},
+
+		{"approve by an admin",
+			createSetInvoiceStatus(t, adminID, tokenApprove,
+				cms.InvoiceStatusApproved, ""),
+			admin, nil, admin.PublicKey()},
+	}
+
+	// Run tests
+	for _, v := range tests {
+		t.Run(v.name, func(t *testing.T) {
+			_, err := p.processSetInvoiceStatus(*v.sis, v.user)
+			got := errToStr(err)
+			want := errToStr(v.want)
+			if got != want {
+				t.Fatalf("got error %v, want %v", got, want)
+			}
+			if err != nil {
+				return
+			}
+
+			inv, err := p.cmsDB.InvoiceByToken(v.sis.Token)
+			if err != nil {
+				t.Fatalf("%v", err)
+			}
+			if inv.Status != v.sis.Status {
+				t.Errorf("got status %v, want %v", inv.Status,
+					v.sis.Status)
+			}
+			c := inv.Changes[len(inv.Changes)-1]
+			if c.AdminPublicKey != v.wantAdminKey {
+				t.Errorf("got admin public key '%v', want '%v'",
+					c.AdminPublicKey, v.wantAdminKey)
+			}
+		})
+	}
+}
+
+func TestProcessNewInvoiceDuplicate(t *testing.T) {
+	// Setup politeiawww and test users
+	p := newTestCMSPoliteiawww(t)
+	defer cleanupTestPoliteiawww(t, p)
+
+	usr, id := newUser(t, p, true, false)
+	otherUsr, otherID := newUser(t, p, true, false)
+
+	// Create the invoices that the user has already submitted.  The
+	// invoice of January is still active and is the only one that
+	// counts towards the one invoice per month limit.
+	var year uint16 = 2019
+	merkle := "merkle"
+	existing := []cmsdatabase.Invoice{
+		{Token: "new", Month: 1, Status: cms.InvoiceStatusNew},
+		{Token: "withdrawn", Month: 2, Status: cms.InvoiceStatusWithdrawn},
+		{Token: "rejected", Month: 3, Status: cms.InvoiceStatusRejected},
+	}
+	for _, v := range existing {
+		v.UserID = usr.ID.String()
+		v.Year = year
+		err := p.cmsDB.NewInvoice(&v)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+	}
+	md, err := encodeBackendInvoiceMetadata(BackendInvoiceMetadata{
+		Version: BackendInvoiceMetadataVersion,
+		Month:   1,
+		Year:    year,
+		UserID:  usr.ID.String(),
+	})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	p.cache.(*testCache).records["new"] = cache.Record{
+		CensorshipRecord: cache.CensorshipRecord{
+			Token:  "new",
+			Merkle: merkle,
+		},
+		Metadata: []cache.MetadataStream{{
+			ID:      mdStreamGeneral,
+			Payload: string(md),
+		}},
+	}
+
+	// Create test data
+	invNoNonce := createNewInvoice(t, id, 1, year)
+	invNonce := createNewInvoice(t, id, 1, year)
+	invNonce.Nonce = cmsutil.InvoiceNonce(merkle, 1, year)
+	invBadNonce := createNewInvoice(t, id, 1, year)
+	invBadNonce.Nonce = cmsutil.InvoiceNonce(merkle, 2, year)
+
+	// Setup tests
+	var tests = []struct {
+		name        string
+		ni          *cms.NewInvoice
+		user        *user.User
+		want        error
+		wantContext []string
+	}{
+		{"duplicate month",
+			invNoNonce, usr,
+			www.UserError{
+				ErrorCode: www.ErrorStatusInvoiceDuplicate,
+			}, nil},
+
+		{"retry of the existing invoice",
+			invNonce, usr,
+			www.UserError{
+				ErrorCode: www.ErrorStatusInvoiceDuplicate,
+			}, []string{"new"}},
+
+		{"nonce of another invoice",
+			invBadNonce, usr,
+			www.UserError{
+				ErrorCode: www.ErrorStatusInvoiceDuplicate,
+			}, nil},
+
+		{"withdrawn month",
+			createNewInvoice(t, id, 2, year), usr, nil, nil},
+
+		{"rejected month",
+			createNewInvoice(t, id, 3, year), usr, nil, nil},
+
+		{"new month",
+			createNewInvoice(t, id, 4, year), usr, nil, nil},
+
+		{"another user",
+			createNewInvoice(t, otherID, 1, year), otherUsr, nil, nil},
+	}
+
+	// Run tests
+	for _, v := range tests {
+		t.Run(v.name, func(t *testing.T) {
+			_, err := p.processNewInvoice(*v.ni, v.user)
+			got := errToStr(err)
+			want := errToStr(v.want)
+			if got != want {
+				t.Fatalf("got error %v, want %v", got, want)
+			}
+
+			var gotContext []string
+			if ue, ok := err.(www.UserError); ok {
+				gotContext = ue.ErrorContext
+			}
+			if !reflect.DeepEqual(gotContext, v.wantContext) {
+				t.Errorf("got error context %v, want %v",
+					gotContext, v.wantContext)
+			}
+		})
+	}
+}
+
+func TestProcessNewInvoiceOnBehalfOf(t *testing.T) {
+	// Setup politeiawww and a politeiad stand-in.  The invoice is sent
+	// to politeiad so that the stored author can be checked.
+	p := newTestCMSPoliteiawww(t)
+	defer cleanupTestPoliteiawww(t, p)
+	p.test = false
+
+	srv := newTestPoliteiad(t, p)
+	defer srv.Close()
+
+	admin, adminID := newUser(t, p, true, true)
+	usr, id := newUser(t, p, true, false)
+	contractor, _ := newUser(t, p, true, false)
+
+	// Create test data
+	var year uint16 = 2019
+	invNotAdmin := createNewInvoice(t, id, 1, year)
+	invNotAdmin.OnBehalfOf = contractor.ID.String()
+	invBadID := createNewInvoice(t, adminID, 1, year)
+	invBadID.OnBehalfOf = "contractor"
+	invUnknownID := createNewInvoice(t, adminID, 1, year)
+	invUnknownID.OnBehalfOf = uuid.New().String()
+	invProxy := createNewInvoice(t, adminID, 1, year)
+	invProxy.OnBehalfOf = contractor.ID.String()
+	invOwn := createNewInvoice(t, adminID, 1, year)
+
+	// Setup tests
+	var tests = []struct {
+		name       string
+		ni         *cms.NewInvoice
+		user       *user.User
+		want       error
+		wantAuthor *user.User
+	}{
+		{"not an admin",
+			invNotAdmin, usr,
+			www.UserError{
+				ErrorCode: www.ErrorStatusInvoiceProxyNotAdmin,
+			}, nil},
+
+		{"invalid user id",
+			invBadID, admin,
+			www.UserError{
+				ErrorCode: www.ErrorStatusUserNotFound,
+			}, nil},
+
+		{"unknown user id",
+			invUnknownID, admin,
+			www.UserError{
+				ErrorCode: www.ErrorStatusUserNotFound,
+			}, nil},
+
+		{"on behalf of a contractor",
+			invProxy, admin, nil, contractor},
+
+		{"admin invoice of the same month",
+			invOwn, admin, nil, admin},
+	}
+
+	// Run tests
+	for _, v := range tests {
+		t.Run(v.name, func(t *testing.T) {
+			nir, err := p.processNewInvoice(*v.ni, v.user)
+			got := errToStr(err)
+			want := errToStr(v.want)
+			if got != want {
+				t.Fatalf("got error %v, want %v", got, want)
+			}
+			if err != nil {
+				return
+			}
+
+			inv, err := p.cmsDB.InvoiceByToken(nir.CensorshipRecord.Token)
+			if err != nil {
+				t.Fatalf("%v", err)
+			}
+			if inv.UserID != v.wantAuthor.ID.String() {
+				t.Errorf("got author %v, want %v", inv.UserID,
+					v.wantAuthor.ID.String())
+			}
+			if inv.PublicKey != v.ni.PublicKey {
+				t.Errorf("got public key %v, want %v", inv.PublicKey,
+					v.ni.PublicKey)
+			}
+		})
+	}
+}
@@ -260,6 +260,22 @@ func (p *politeiawww) handleProposalDetails(w http.ResponseWriter, r *http.Reque
 	util.RespondWithJSON(w, http.StatusOK, reply)
 }
 
+// validMIMETypes returns the MIME types that are accepted in the passed in
+// mode.  politeiad stores PDF files for invoices, but they are not accepted
+// for proposals so they are only advertised in cmswww mode.
+func validMIMETypes(mode string) []string {
+	if mode == cmsWWWMode {
+		return mime.ValidMimeTypes()
+	}
+	types := make([]string, 0, len(mime.ValidMimeTypes()))
+	for _, v := range mime.ValidMimeTypes() {
+		if v != "application/pdf" {
+			types = append(types, v)
+		}
+	}
+	return types
+}
+
 func (p *politeiawww) handlePolicy(w http.ResponseWriter, r *http.Request) {
 	// Get the policy command.
 	log.Tracef("handlePolicy")
@@ -274,7 +290,7 @@ func (p *politeiawww) handlePolicy(w http.ResponseWriter, r *http.Request) {
 		MaxImageSize:               www.PolicyMaxImageSize,
 		MaxMDs:                     www.PolicyMaxMDs,
 		MaxMDSize:                  www.PolicyMaxMDSize,
-		ValidMIMETypes:             mime.ValidMimeTypes(),
+		ValidMIMETypes:             validMIMETypes(p.cfg.Mode),
 		MinProposalNameLength:      www.PolicyMinProposalNameLength,
 		MaxProposalNameLength:      www.PolicyMaxProposalNameLength,
 		ProposalNameSupportedChars: www.PolicyProposalNameSupportedChars,
@@ -289,6 +305,12 @@ func (p *politeiawww) handlePolicy(w http.ResponseWriter, r *http.Request) {
 		reply.InvoiceCommentChar = www.PolicyInvoiceCommentChar
 		reply.InvoiceFieldDelimiterChar = www.PolicyInvoiceFieldDelimiterChar
 		reply.InvoiceLineItemCount = www.PolicyInvoiceLineItemCount
+		reply.InvoiceMaxSubtypeLength = www.PolicyInvoiceMaxSubtypeLength
+		reply.InvoiceMaxDescLength = www.PolicyInvoiceMaxDescriptionLength
+		reply.MaxPDFs = www.PolicyMaxPDFs
+		reply.MaxPDFSize = www.PolicyMaxPDFSize
+		reply.InvoiceLineItemSubtypes = p.cfg.InvoiceLineItemSubtypes
+		reply.InvoiceWindow = p.invoiceWindow()
 	}
 
 	util.RespondWithJSON(w, http.StatusOK, reply)
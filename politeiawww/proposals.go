@@ -196,7 +196,14 @@ func validateProposal(np www.NewProposal, u *user.User) error {
 			data []byte
 			err  error
 		)
-		if strings.HasPrefix(v.MIME, "image/") {
+		switch {
+		case v.MIME == "application/pdf":
+			// PDF files are only accepted as invoice attachments
+			return www.UserError{
+				ErrorCode:    www.ErrorStatusUnsupportedMIMEType,
+				ErrorContext: []string{v.Name},
+			}
+		case strings.HasPrefix(v.MIME, "image/"):
 			numImages++
 			data, err = base64.StdEncoding.DecodeString(v.Payload)
 			if err != nil {
@@ -205,7 +212,7 @@ func validateProposal(np www.NewProposal, u *user.User) error {
 			if len(data) > www.PolicyMaxImageSize {
 				imageExceedsMaxSize = true
 			}
-		} else {
+		default:
 			numMDs++
 
 			if v.Name == indexFile {
@@ -1378,6 +1385,7 @@ func (p *politeiawww) getVoteStatus(token string, bestBlock uint64) (*www.VoteSt
 		NumOfEligibleVotes: len(vd.StartVoteReply.EligibleTickets),
 		QuorumPercentage:   vd.StartVote.Vote.QuorumPercentage,
 		PassPercentage:     vd.StartVote.Vote.PassPercentage,
+		BestBlock:          strconv.FormatUint(bestBlock, 10),
 	}, nil
 }
 
@@ -13,11 +13,15 @@ import (
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"sync"
 	"testing"
 
 	"github.com/decred/dcrd/chaincfg"
+	pd "github.com/decred/politeia/politeiad/api/v1"
 	"github.com/decred/politeia/politeiad/api/v1/identity"
+	"github.com/decred/politeia/politeiad/cache"
 	www "github.com/decred/politeia/politeiawww/api/www/v1"
+	"github.com/decred/politeia/politeiawww/cmsdatabase"
 	"github.com/decred/politeia/politeiawww/user"
 	"github.com/decred/politeia/politeiawww/user/localdb"
 	"github.com/decred/politeia/util"
@@ -244,3 +248,218 @@ func newTestPoliteiawww(t *testing.T) *politeiawww {
 
 	return &p
 }
+
+// testCMSDB is an in-memory cmsdatabase.Database that is used for testing.
+type testCMSDB struct {
+	sync.RWMutex
+	invoices map[string]cmsdatabase.Invoice // [token]invoice
+}
+
+// NewInvoice stores a new invoice.
+//
+// This function satisfies the cmsdatabase.Database interface.
+func (db *testCMSDB) NewInvoice(inv *cmsdatabase.Invoice) error {
+	db.Lock()
+	defer db.Unlock()
+
+	db.invoices[inv.Token] = *inv
+	return nil
+}
+
+// UpdateInvoice replaces an existing invoice.
+//
+// This function satisfies the cmsdatabase.Database interface.
+func (db *testCMSDB) UpdateInvoice(inv *cmsdatabase.Invoice) error {
+	db.Lock()
+	defer db.Unlock()
+
+	if _, ok := db.invoices[inv.Token]; !ok {
+		return cmsdatabase.ErrInvoiceNotFound
+	}
+	db.invoices[inv.Token] = *inv
+	return nil
+}
+
+// InvoicesByUserID returns the invoices of the given user.
+//
+// This function satisfies the cmsdatabase.Database interface.
+func (db *testCMSDB) InvoicesByUserID(userID string) ([]cmsdatabase.Invoice, error) {
+	return db.filter(func(inv cmsdatabase.Invoice) bool {
+		return inv.UserID == userID
+	}), nil
+}
+
+// InvoiceByToken returns the invoice with the given token.
+//
+// This function satisfies the cmsdatabase.Database interface.
+func (db *testCMSDB) InvoiceByToken(token string) (*cmsdatabase.Invoice, error) {
+	db.RLock()
+	defer db.RUnlock()
+
+	inv, ok := db.invoices[token]
+	if !ok {
+		return nil, cmsdatabase.ErrInvoiceNotFound
+	}
+	return &inv, nil
+}
+
+// InvoicesByMonthYearStatus returns the invoices of the given month and year
+// that have the given status.
+//
+// This function satisfies the cmsdatabase.Database interface.
+func (db *testCMSDB) InvoicesByMonthYearStatus(month, year uint16, status int) ([]cmsdatabase.Invoice, error) {
+	return db.filter(func(inv cmsdatabase.Invoice) bool {
+		return inv.Month == month && inv.Year == year &&
+			int(inv.Status) == status
+	}), nil
+}
+
+// InvoicesByMonthYear returns the invoices of the given month and year.
+//
+// This function satisfies the cmsdatabase.Database interface.
+func (db *testCMSDB) InvoicesByMonthYear(month, year uint16) ([]cmsdatabase.Invoice, error) {
+	return db.filter(func(inv cmsdatabase.Invoice) bool {
+		return inv.Month == month && inv.Year == year
+	}), nil
+}
+
+// InvoicesByStatus returns the invoices that have the given status.
+//
+// This function satisfies the cmsdatabase.Database interface.
+func (db *testCMSDB) InvoicesByStatus(status int) ([]cmsdatabase.Invoice, error) {
+	return db.filter(func(inv cmsdatabase.Invoice) bool {
+		return int(inv.Status) == status
+	}), nil
+}
+
+// InvoicesAll returns all invoices.
+//
+// This function satisfies the cmsdatabase.Database interface.
+func (db *testCMSDB) InvoicesAll() ([]cmsdatabase.Invoice, error) {
+	return db.filter(func(inv cmsdatabase.Invoice) bool {
+		return true
+	}), nil
+}
+
+// Setup is a no-op.
+//
+// This function satisfies the cmsdatabase.Database interface.
+func (db *testCMSDB) Setup() error {
+	return nil
+}
+
+// Build is a no-op.
+//
+// This function satisfies the cmsdatabase.Database interface.
+func (db *testCMSDB) Build(string) error {
+	return nil
+}
+
+// Close is a no-op.
+//
+// This function satisfies the cmsdatabase.Database interface.
+func (db *testCMSDB) Close() error {
+	return nil
+}
+
+// filter returns the invoices for which keep returns true.
+func (db *testCMSDB) filter(keep func(cmsdatabase.Invoice) bool) []cmsdatabase.Invoice {
+	db.RLock()
+	defer db.RUnlock()
+
+	invs := make([]cmsdatabase.Invoice, 0, len(db.invoices))
+	for _, v := range db.invoices {
+		if keep(v) {
+			invs = append(invs, v)
+		}
+	}
+	return invs
+}
+
+// testCache is an in-memory records cache that is used for testing.  Only
+// the methods that are used by the tests are implemented; the others panic.
+type testCache struct {
+	cache.Cache
+
+	sync.RWMutex
+	records map[string]cache.Record // [token]record
+}
+
+// Record returns the record with the given token.
+func (c *testCache) Record(token string) (*cache.Record, error) {
+	c.RLock()
+	defer c.RUnlock()
+
+	r, ok := c.records[token]
+	if !ok {
+		return nil, cache.ErrRecordNotFound
+	}
+	return &r, nil
+}
+
+// newTestPoliteiad returns an httptest server that stands in for politeiad.
+// It answers every request with a signed challenge response and a random
+// censorship token.  The politeiawww context is pointed at the server and
+// the caller must close it.
+func newTestPoliteiad(t *testing.T, p *politeiawww) *httptest.Server {
+	t.Helper()
+
+	id, err := identity.New()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Challenge string `json:"challenge"`
+		}
+		err := json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		challenge, err := hex.DecodeString(req.Challenge)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		token, err := util.Random(pd.TokenSize)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		response := id.SignMessage(challenge)
+
+		// Every politeiad reply carries the challenge response.  The
+		// censorship record is ignored by the replies that don't have
+		// one.
+		util.RespondWithJSON(w, http.StatusOK, pd.NewRecordReply{
+			Response: hex.EncodeToString(response[:]),
+			CensorshipRecord: pd.CensorshipRecord{
+				Token: hex.EncodeToString(token),
+			},
+		})
+	}))
+
+	p.cfg.Identity = &id.Public
+	p.cfg.RPCHost = srv.URL
+
+	return srv
+}
+
+// newTestCMSPoliteiawww returns a new politeiawww context that is setup for
+// testing in cmswww mode.
+func newTestCMSPoliteiawww(t *testing.T) *politeiawww {
+	t.Helper()
+
+	p := newTestPoliteiawww(t)
+	p.cmsDB = &testCMSDB{
+		invoices: make(map[string]cmsdatabase.Invoice),
+	}
+	p.cache = &testCache{
+		records: make(map[string]cache.Record),
+	}
+	p.eventManager = &EventManager{}
+
+	return p
+}
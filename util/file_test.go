@@ -0,0 +1,52 @@
+// Copyright (c) 2017-2019 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package util
+
+import (
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestCleanAndExpandPath(t *testing.T) {
+	u, err := user.Current()
+	if err != nil {
+		t.Skipf("user.Current: %v", err)
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"invoices/jan.csv", filepath.FromSlash("invoices/jan.csv")},
+		{"invoices/./receipts/../jan.csv",
+			filepath.FromSlash("invoices/jan.csv")},
+		{"~/invoices/jan.csv",
+			filepath.Join(u.HomeDir, "invoices", "jan.csv")},
+	}
+
+	// Windows paths may use either separator
+	if runtime.GOOS == "windows" {
+		tests = append(tests, []struct {
+			path string
+			want string
+		}{
+			{`invoices\jan.csv`, `invoices\jan.csv`},
+			{`invoices/receipts\..\jan.csv`, `invoices\jan.csv`},
+			{`~\invoices\jan.csv`,
+				filepath.Join(u.HomeDir, "invoices", "jan.csv")},
+			{`C:\invoices/jan.csv`, `C:\invoices\jan.csv`},
+		}...)
+	}
+
+	for _, test := range tests {
+		got := CleanAndExpandPath(test.path)
+		if got != test.want {
+			t.Errorf("CleanAndExpandPath(%q): got %q, want %q", test.path,
+				got, test.want)
+		}
+	}
+}